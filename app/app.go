@@ -4,28 +4,41 @@ import (
 	"anon-bestdori-database/config"
 	"anon-bestdori-database/data"
 	"anon-bestdori-database/database"
+	"anon-bestdori-database/files"
 	"anon-bestdori-database/pkg/log"
 	"anon-bestdori-database/server"
 	"anon-bestdori-database/version"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
+	"time"
 )
 
 var stoppedChan = make(chan bool, 1)
 
+// defaultShutdownTimeout 未配置关闭超时时的默认值
+const defaultShutdownTimeout = 30 * time.Second
+
+// reloadShutdownMargin 重载时等待旧服务停止的时间在关闭超时之外额外留出的余量
+const reloadShutdownMargin = 5 * time.Second
+
 func Stopped() chan bool {
 	return stoppedChan
 }
 
 type app struct {
-	ctx     context.Context
-	cancel  context.CancelFunc
-	conf    *config.Config
-	db      *database.Database
-	updater *data.DataUpdater
-	server  *server.Server
+	ctx          context.Context
+	cancel       context.CancelFunc
+	conf         *config.Config
+	db           *database.Database
+	updater      *data.DataUpdater
+	server       *server.Server
+	serverCancel context.CancelFunc
+	serverDone   chan struct{}
 }
 
 var appInstance *app
@@ -42,7 +55,7 @@ func newApp(conf *config.Config) (*app, error) {
 	log.Infof("connection with database established: %s", conf.Mongo.URI)
 
 	updater := data.NewDataUpdater(db, conf, ctx)
-	srv := server.New(db, updater)
+	srv := server.New(conf, db, updater)
 
 	return &app{
 		ctx:     ctx,
@@ -69,31 +82,180 @@ func (a *app) Run() {
 	log.Info("scheduled update started")
 
 	addr := net.JoinHostPort(a.conf.Server.Host, a.conf.Server.Port)
+	cancel, done, err := a.startServer(a.server, addr)
+	if err != nil {
+		log.Errorf("failed to run fiber server: %v", err)
+		stoppedChan <- true
+		return
+	}
+	a.serverCancel = cancel
+	a.serverDone = done
+}
+
+// startServer 监听地址并在后台运行 HTTP 服务，监听失败时直接返回错误
+//
+// 返回停止服务的函数与服务完全停止后关闭的通道
+func (a *app) startServer(srv *server.Server, addr string) (context.CancelFunc, chan struct{}, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	done := make(chan struct{})
+
 	log.Infof("application listening on %s", addr)
 	go func() {
-		if err := a.server.Start(a.ctx, addr); err != nil && !strings.Contains(err.Error(), "server closed") {
+		defer close(done)
+		err := srv.Serve(ctx, ln)
+		switch {
+		case err == nil || strings.Contains(err.Error(), "server closed"):
+			log.Info("fiber server stopped")
+		case ctx.Err() != nil:
+			log.Warnf("fiber server shutdown incomplete: %v", err)
+		default:
 			log.Errorf("failed to run fiber server: %v", err)
-			stoppedChan <- true
-			return
+			select {
+			case stoppedChan <- true:
+			default:
+			}
 		}
-		log.Info("fiber server stopped")
 	}()
+	return cancel, done, nil
 }
 
+// stopServer 停止接受新请求并等待进行中的请求完成
+func (a *app) stopServer(ctx context.Context) {
+	if a.serverCancel == nil {
+		return
+	}
+	a.serverCancel()
+	select {
+	case <-a.serverDone:
+	case <-ctx.Done():
+		log.Warnf("timed out waiting for fiber server to stop")
+	}
+}
+
+// reload 比较新旧配置，仅重新应用或重启配置发生变化的组件
+func (a *app) reload(old *config.Config) error {
+	keys := config.ChangedKeys(old, a.conf)
+	if len(keys) == 0 {
+		log.Info("config unchanged")
+		return nil
+	}
+	log.Infof("config changed: %s", strings.Join(keys, ", "))
+
+	changed := func(match func(key string) bool) bool {
+		return slices.ContainsFunc(keys, match)
+	}
+	hasPrefix := func(prefixes ...string) func(string) bool {
+		return func(key string) bool {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(key, prefix) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	// 数据库连接被所有组件共享，只能整体重启
+	if changed(hasPrefix("mongo.")) {
+		log.Info("mongo config changed, rebooting application")
+		return ReBoot(a.conf)
+	}
+
+	if changed(hasPrefix("log.")) {
+		if err := log.Reload(a.conf); err != nil {
+			log.Errorf("failed to apply log config: %v", err)
+		}
+	}
+
+	if changed(func(key string) bool {
+		return hasPrefix("assets.", "memory_cache.")(key) && !strings.HasPrefix(key, "assets.mirror.")
+	}) {
+		if err := files.FlushManifest(); err != nil {
+			log.Errorf("failed to save assets manifest: %v", err)
+		}
+		if err := files.Init(a.conf); err != nil {
+			log.Errorf("failed to reinitialize assets storage: %v", err)
+		} else {
+			log.Info("assets storage reconfigured")
+		}
+	}
+
+	if changed(hasPrefix("api.", "assets.mirror.")) {
+		a.updater.Reconfigure(a.conf)
+	}
+
+	// 先在新地址上开始监听，成功后再关闭旧服务，等待旧服务中进行的请求完成后再切换
+	if changed(hasPrefix("server.host", "server.port")) {
+		addr := net.JoinHostPort(a.conf.Server.Host, a.conf.Server.Port)
+		srv := server.New(a.conf, a.db, a.updater)
+		cancel, done, err := a.startServer(srv, addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s, keeping previous server: %w", addr, err)
+		}
+		// 旧服务卡住时不能阻塞信号循环，超时后记录日志并继续切换
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), a.shutdownTimeout()+reloadShutdownMargin)
+		a.stopServer(stopCtx)
+		stopCancel()
+		a.server = srv
+		a.serverCancel = cancel
+		a.serverDone = done
+	}
+	return nil
+}
+
+// shutdownTimeout 获取 server.shutdown_timeout 指定的关闭超时，未配置时使用默认值
+func (a *app) shutdownTimeout() time.Duration {
+	if timeout := time.Duration(a.conf.Server.ShutdownTimeout) * time.Second; timeout > 0 {
+		return timeout
+	}
+	return defaultShutdownTimeout
+}
+
+// Close 按顺序关闭应用：停止接受请求并等待进行中的请求，停止数据更新器并等待，最后关闭数据库
+//
+// 全部步骤共享 server.shutdown_timeout 指定的超时时间
 func (a *app) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout())
+	defer cancel()
+
+	log.Info("stopping fiber server...")
+	a.stopServer(ctx)
+
+	log.Info("stopping data updater...")
+	if err := a.updater.Stop(ctx); err != nil {
+		log.Warnf("timed out waiting for data updater to stop: %v", err)
+	}
+
+	if err := files.FlushManifest(); err != nil {
+		log.Errorf("failed to save assets manifest: %v", err)
+	}
 	log.Info("closing connection with database...")
-	a.db.Close(a.ctx)
+	if err := a.db.Close(ctx); err != nil {
+		log.Errorf("failed to close database connection: %v", err)
+	}
 	a.cancel()
 	log.Info("application stopped")
 }
 
 func Run(conf *config.Config, init bool) error {
-	log.Init(conf, "anon-bestdori-database")
+	// SIGHUP 重启时同样经过此处，日志输出与轮转配置随之生效
+	if err := log.Init(conf, "anon-bestdori-database"); err != nil {
+		log.Errorf("failed to apply log config: %v", err)
+	}
 
 	if appInstance != nil {
 		return fmt.Errorf("application is running")
 	}
 
+	if err := files.Init(conf); err != nil {
+		log.Errorf("failed to initialize assets storage: %v", err)
+		return err
+	}
+
 	app, err := newApp(conf)
 	if err != nil {
 		return err
@@ -114,15 +276,126 @@ func Run(conf *config.Config, init bool) error {
 	return nil
 }
 
+// Record 从上游完整抓取一次数据并录制为本地快照，不连接数据库
+func Record(conf *config.Config, dir string, postLimit int) error {
+	log.Init(conf, "anon-bestdori-database")
+
+	log.Infof("recording upstream snapshot to %s ...", dir)
+	if err := data.RecordSnapshot(context.Background(), data.NewSource(conf), dir, postLimit); err != nil {
+		log.Errorf("failed to record snapshot: %v", err)
+		return err
+	}
+	log.Infof("snapshot recorded to %s", dir)
+	return nil
+}
+
+// VerifyAssets 校验全部资源，重新下载损坏或缺失的资源并报告孤立资源
+func VerifyAssets(conf *config.Config) error {
+	log.Init(conf, "anon-bestdori-database")
+
+	if err := files.Init(conf); err != nil {
+		log.Errorf("failed to initialize assets storage: %v", err)
+		return err
+	}
+
+	app, err := newApp(conf)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+
+	log.Info("verifying assets ...")
+	result, err := app.updater.VerifyAssets()
+	if err != nil {
+		log.Errorf("failed to verify assets: %v", err)
+		return err
+	}
+
+	for _, name := range result.Corrupt {
+		log.Warnf("corrupt assets removed: %s", name)
+	}
+	for _, name := range result.Missing {
+		log.Warnf("assets missing from storage: %s", name)
+	}
+	for _, name := range result.Orphans {
+		log.Warnf("orphan assets: %s", name)
+	}
+	for _, name := range result.Failed {
+		log.Errorf("failed to repair assets: %s", name)
+	}
+	log.Infof(
+		"assets verification completed: %d checked, %d corrupt, %d missing, %d adopted, %d repaired, %d failed, %d orphans",
+		result.Checked,
+		len(result.Corrupt),
+		len(result.Missing),
+		len(result.Adopted),
+		len(result.Repaired),
+		len(result.Failed),
+		len(result.Orphans),
+	)
+
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d assets could not be repaired", len(result.Failed))
+	}
+	return nil
+}
+
+// AddAPIKey 生成新的 API Key 并保存到数据库，密钥仅在此时输出一次
+func AddAPIKey(conf *config.Config, name string) error {
+	log.Init(conf, "anon-bestdori-database")
+
+	ctx := context.Background()
+	db, err := database.NewClient(ctx, conf)
+	if err != nil {
+		log.Errorf("failed to connect to database: %v", err)
+		return err
+	}
+	defer db.Close(ctx)
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	key := hex.EncodeToString(b)
+
+	err = db.InsertAPIKey(ctx, &database.APIKey{
+		Hash:      database.HashAPIKey(key),
+		Name:      name,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Errorf("failed to save api key: %v", err)
+		return err
+	}
+	log.Infof("api key %s created", name)
+	fmt.Println(key)
+	return nil
+}
+
 func Stop() {
 	if appInstance == nil {
 		log.Error("no application running")
+		return
 	}
 
 	appInstance.Close()
 	appInstance = nil
 }
 
+// Reload 重新读取并发布配置，仅重启配置发生变化的组件，重载期间持续提供服务
+func Reload() error {
+	if appInstance == nil {
+		return fmt.Errorf("no application running")
+	}
+
+	old, err := config.Reload()
+	if err != nil {
+		return err
+	}
+	appInstance.conf = config.Get()
+	return appInstance.reload(old)
+}
+
 func ReBoot(conf *config.Config) error {
 	log.Info("application rebooting...")
 	Stop()
@@ -3,106 +3,256 @@ package config
 import (
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"sync/atomic"
 
 	"github.com/spf13/viper"
 )
 
+type RouteAuthConfig struct {
+	RequireKey bool `mapstructure:"require_key"`
+	KeyLimit   int  `mapstructure:"key_limit"` // 每个 API Key 每个窗口的请求数上限，0 表示不限制
+	IPLimit    int  `mapstructure:"ip_limit"`  // 未携带 API Key 时每个 IP 每个窗口的请求数上限，0 表示不限制
+}
+
+type AuthConfig struct {
+	Enabled bool            `mapstructure:"enabled"`
+	Keys    []string        `mapstructure:"keys" secret:"true"` // 形如 name:key，也可存放在数据库 api_keys 集合中
+	Window  int             `mapstructure:"window"`             // 限流窗口（秒）
+	Posts   RouteAuthConfig `mapstructure:"posts"`
+	Songs   RouteAuthConfig `mapstructure:"songs"`
+	Charts  RouteAuthConfig `mapstructure:"charts"`
+	Assets  RouteAuthConfig `mapstructure:"assets"`
+}
+
+type CORSPolicyConfig struct {
+	AllowOrigins     []string `mapstructure:"allow_origins"` // 支持 * 与 https://*.example.com 形式的子域名通配
+	AllowMethods     []string `mapstructure:"allow_methods"`
+	AllowHeaders     []string `mapstructure:"allow_headers"`
+	ExposeHeaders    []string `mapstructure:"expose_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"` // 预检请求结果的缓存时间（秒），0 表示不设置
+}
+
+type CORSConfig struct {
+	Default CORSPolicyConfig `mapstructure:"default"`
+	// 路由组策略，allow_origins 为空时使用默认策略，其余未设置的项沿用默认策略中的值
+	Posts  CORSPolicyConfig `mapstructure:"posts"`
+	Songs  CORSPolicyConfig `mapstructure:"songs"`
+	Charts CORSPolicyConfig `mapstructure:"charts"`
+	Assets CORSPolicyConfig `mapstructure:"assets"`
+}
+
+type CacheControlConfig struct {
+	Posts          string `mapstructure:"posts"`
+	PostsSearch    string `mapstructure:"posts_search"`
+	Songs          string `mapstructure:"songs"`
+	SongsSearch    string `mapstructure:"songs_search"`
+	Charts         string `mapstructure:"charts"`
+	AssetsManifest string `mapstructure:"assets_manifest"`
+}
+
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port string `mapstructure:"port"`
+	Host            string     `mapstructure:"host"`
+	Port            string     `mapstructure:"port"`
+	ShutdownTimeout int        `mapstructure:"shutdown_timeout"` // 关闭时等待请求与数据更新完成的最长时间（秒）
+	ProxyHeader     string     `mapstructure:"proxy_header"`     // 反向代理传递客户端 IP 的请求头，如 X-Forwarded-For
+	Auth            AuthConfig `mapstructure:"auth"`
+	CORS            CORSConfig `mapstructure:"cors"`
+	Compress        bool       `mapstructure:"compress"` // 按 Accept-Encoding 压缩 JSON 响应
+	// JSON 接口成功响应的 Cache-Control，为空时不设置
+	CacheControl CacheControlConfig `mapstructure:"cache_control"`
 }
 
 type MongoConfig struct {
-	URI string `mapstructure:"uri"`
+	URI string `mapstructure:"uri" secret:"true"`
+}
+
+type LogFileConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Path       string `mapstructure:"path"`        // 为空时使用 log/{程序名}.log
+	MaxSize    int    `mapstructure:"max_size"`    // MB
+	MaxBackups int    `mapstructure:"max_backups"` // 保留的旧日志文件数量
+	MaxAge     int    `mapstructure:"max_age"`     // 天
+	Compress   bool   `mapstructure:"compress"`
 }
 
 type LogConfig struct {
-	Level string `mapstructure:"level"`
+	Level  string        `mapstructure:"level"`
+	Format string        `mapstructure:"format"` // text 或 json
+	Stdout bool          `mapstructure:"stdout"`
+	File   LogFileConfig `mapstructure:"file"`
 }
 
 type APIConfig struct {
-	Timeout int    `mapstructure:"timeout"`
-	Proxy   string `mapstructure:"proxy"`
-	Retry   int    `mapstructure:"retry"`
-	Gap     int    `mapstructure:"gap"`
+	Timeout        int    `mapstructure:"timeout"`
+	Proxy          string `mapstructure:"proxy" secret:"true"`
+	Retry          int    `mapstructure:"retry"`
+	Gap            int    `mapstructure:"gap"`
+	UpdateInterval int    `mapstructure:"update_interval"` // 定时更新间隔（分钟）
+	BaseURL        string `mapstructure:"base_url"`
+	Snapshot       string `mapstructure:"snapshot"`
+}
+
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	AccessKey string `mapstructure:"access_key" secret:"true"`
+	SecretKey string `mapstructure:"secret_key" secret:"true"`
+	Bucket    string `mapstructure:"bucket"`
+	Region    string `mapstructure:"region"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+	Prefix    string `mapstructure:"prefix"`
+}
+
+type ImageConfig struct {
+	Sizes         []int    `mapstructure:"sizes"`
+	WebPCommand   []string `mapstructure:"webp_command"`
+	AVIFCommand   []string `mapstructure:"avif_command"`
+	EncodeTimeout int      `mapstructure:"encode_timeout"` // 单次编码的超时时间（秒），超时后返回原图
+}
+
+type AudioConfig struct {
+	FFmpeg          string `mapstructure:"ffmpeg"`
+	OpusBitrate     string `mapstructure:"opus_bitrate"`
+	PreviewOffset   int    `mapstructure:"preview_offset"`
+	PreviewDuration int    `mapstructure:"preview_duration"`
+	EncodeTimeout   int    `mapstructure:"encode_timeout"` // 单次转码的超时时间（秒），超时后返回原音频
+}
+
+type MirrorConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	BaseURL       string `mapstructure:"base_url"`
+	MaxAudioBytes int64  `mapstructure:"max_audio_bytes"`
+	MaxCoverBytes int64  `mapstructure:"max_cover_bytes"`
+	Timeout       int    `mapstructure:"timeout"`
+}
+
+type AssetsConfig struct {
+	Backend       string       `mapstructure:"backend"`
+	Path          string       `mapstructure:"path"`
+	Redirect      bool         `mapstructure:"redirect"`
+	PresignExpiry int          `mapstructure:"presign_expiry"`
+	S3            S3Config     `mapstructure:"s3"`
+	Image         ImageConfig  `mapstructure:"image"`
+	Audio         AudioConfig  `mapstructure:"audio"`
+	Mirror        MirrorConfig `mapstructure:"mirror"`
+}
+
+type MemoryCacheConfig struct {
+	AssetsMaxBytes int64 `mapstructure:"assets_max_bytes"`
+	CacheMaxBytes  int64 `mapstructure:"cache_max_bytes"`
+	MaxEntryBytes  int64 `mapstructure:"max_entry_bytes"`
+	CacheAudio     bool  `mapstructure:"cache_audio"`
+}
+
+type CacheConfig struct {
+	Path string `mapstructure:"path"` // 更新进度等缓存文件的目录
 }
 
 type Config struct {
-	Mongo  MongoConfig  `mapstructure:"mongo"`
-	Log    LogConfig    `mapstructure:"log"`
-	API    APIConfig    `mapstructure:"api"`
-	Server ServerConfig `mapstructure:"server"`
-}
-
-var configPaths = []string{
-	"mongo.uri",
-	"log.level",
-	"api.timeout",
-	"api.proxy",
-	"api.retry",
-	"api.gap",
-	"server.host",
-	"server.port",
-}
-
-func applyEnvOverrides() {
-	for _, path := range configPaths {
-		if viper.IsSet(path) {
-			continue
-		}
-		envKey := "ANON_DATABASE_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
-		if val := os.Getenv(envKey); val != "" {
-			viper.Set(path, val)
-		}
+	Mongo       MongoConfig       `mapstructure:"mongo"`
+	Log         LogConfig         `mapstructure:"log"`
+	API         APIConfig         `mapstructure:"api"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Assets      AssetsConfig      `mapstructure:"assets"`
+	Cache       CacheConfig       `mapstructure:"cache"`
+	MemoryCache MemoryCacheConfig `mapstructure:"memory_cache"`
+}
+
+// configFile 通过 -config 指定的配置文件路径
+var configFile string
+
+// SetConfigFile 指定配置文件路径，为空时依次使用 ANON_DATABASE_CONFIG 与当前目录下的 config.yaml
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// resolveConfigFile 获取显式指定的配置文件路径
+func resolveConfigFile() string {
+	if configFile != "" {
+		return configFile
 	}
+	return os.Getenv("ANON_DATABASE_CONFIG")
 }
 
+// Load 读取配置文件并合并环境变量与默认值
+//
+// 不会写入任何文件，需要配置模板时使用 WriteDefaults
 func Load() (*Config, error) {
 	viper.Reset()
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-
-	// 优先 yaml
-	err := viper.ReadInConfig()
-
-	var hasYaml bool
-	if err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, err
+	if path := resolveConfigFile(); path != "" {
+		// 显式指定的配置文件必须存在
+		viper.SetConfigFile(path)
+		if filepath.Ext(path) == "" {
+			viper.SetConfigType("yaml")
+		}
+		if err := viper.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
 		}
-		fmt.Println("config.yaml not found, using ENV and defaults.")
-		hasYaml = false
 	} else {
-		hasYaml = true
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+
+		// 优先 yaml
+		if err := viper.ReadInConfig(); err != nil {
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				return nil, err
+			}
+			fmt.Println("config.yaml not found, using ENV and defaults.")
+		}
 	}
 
-	applyEnvOverrides()
+	if err := applyEnvOverrides(); err != nil {
+		return nil, err
+	}
 
-	setDefaults()
+	setDefaults(viper.GetViper())
 
 	cfg := &Config{}
 	if err := viper.Unmarshal(cfg); err != nil {
 		return nil, err
 	}
 
-	if !hasYaml {
-		if err := viper.WriteConfigAs("config.yaml"); err != nil {
-			fmt.Printf("failed to create config.yaml: %v\n", err)
-		} else {
-			fmt.Println("Created config.yaml with current configuration.")
-		}
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
 	}
 
 	return cfg, nil
 }
-func (c *Config) Reload() error {
+
+// WriteDefaults 将默认配置写入 path 作为配置模板，文件已存在时返回错误
+//
+// 不包含来自配置文件、环境变量与 _FILE 的值，避免敏感信息以明文落盘
+func WriteDefaults(path string) error {
+	v := viper.New()
+	setDefaults(v)
+	if filepath.Ext(path) == "" {
+		v.SetConfigType("yaml")
+	}
+	return v.SafeWriteConfigAs(path)
+}
+
+// current 当前生效的配置快照，发布后不再修改
+var current atomic.Pointer[Config]
+
+// Get 获取当前生效的配置快照，调用方不得修改返回的配置
+func Get() *Config {
+	return current.Load()
+}
+
+// Set 发布新的配置快照
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Reload 重新读取配置并发布为新的快照，返回此前的配置快照
+func Reload() (*Config, error) {
 	cfg, err := Load()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	*c = *cfg
-	return nil
+	return current.Swap(cfg), nil
 }
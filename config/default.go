@@ -2,9 +2,10 @@ package config
 
 import "github.com/spf13/viper"
 
-func setDefaults() {
-	for _, path := range configPaths {
-		if viper.IsSet(path) {
+// setDefaults 为 v 中未设置的配置项填充默认值
+func setDefaults(v *viper.Viper) {
+	for _, path := range configKeys() {
+		if v.IsSet(path) {
 			continue
 		}
 		var defVal any
@@ -13,6 +14,22 @@ func setDefaults() {
 			defVal = "mongodb://localhost:27017/"
 		case "log.level":
 			defVal = "info"
+		case "log.format":
+			defVal = "text"
+		case "log.stdout":
+			defVal = true
+		case "log.file.enabled":
+			defVal = true
+		case "log.file.path":
+			defVal = ""
+		case "log.file.max_size":
+			defVal = 10
+		case "log.file.max_backups":
+			defVal = 14
+		case "log.file.max_age":
+			defVal = 1
+		case "log.file.compress":
+			defVal = true
 		case "api.timeout":
 			defVal = 5
 		case "api.proxy":
@@ -21,11 +38,176 @@ func setDefaults() {
 			defVal = 5
 		case "api.gap":
 			defVal = 10
+		case "api.update_interval":
+			defVal = 10
+		case "api.base_url":
+			defVal = ""
+		case "api.snapshot":
+			defVal = ""
 		case "server.host":
 			defVal = "0.0.0.0"
 		case "server.port":
 			defVal = "8080"
+		case "server.shutdown_timeout":
+			defVal = 30
+		case "server.proxy_header":
+			defVal = ""
+		case "server.cors.default.allow_origins":
+			defVal = []string{"*"}
+		case "server.cors.default.allow_methods":
+			defVal = []string{"GET", "POST", "OPTIONS"}
+		case "server.cors.default.allow_headers":
+			defVal = []string{"*"}
+		case "server.cors.default.expose_headers":
+			defVal = []string{"X-Request-ID", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"}
+		case "server.cors.default.allow_credentials":
+			defVal = false
+		case "server.cors.default.max_age":
+			defVal = 0
+		case "server.cors.posts.allow_origins",
+			"server.cors.posts.allow_methods",
+			"server.cors.posts.allow_headers",
+			"server.cors.posts.expose_headers":
+			defVal = []string{}
+		case "server.cors.posts.allow_credentials":
+			defVal = false
+		case "server.cors.posts.max_age":
+			defVal = 0
+		case "server.cors.songs.allow_origins",
+			"server.cors.songs.allow_methods",
+			"server.cors.songs.allow_headers",
+			"server.cors.songs.expose_headers":
+			defVal = []string{}
+		case "server.cors.songs.allow_credentials":
+			defVal = false
+		case "server.cors.songs.max_age":
+			defVal = 0
+		case "server.cors.charts.allow_origins",
+			"server.cors.charts.allow_methods",
+			"server.cors.charts.allow_headers",
+			"server.cors.charts.expose_headers":
+			defVal = []string{}
+		case "server.cors.charts.allow_credentials":
+			defVal = false
+		case "server.cors.charts.max_age":
+			defVal = 0
+		case "server.cors.assets.allow_origins",
+			"server.cors.assets.allow_methods",
+			"server.cors.assets.allow_headers",
+			"server.cors.assets.expose_headers":
+			defVal = []string{}
+		case "server.cors.assets.allow_credentials":
+			defVal = false
+		case "server.cors.assets.max_age":
+			defVal = 0
+		case "server.compress":
+			defVal = true
+		case "server.cache_control.posts":
+			defVal = "public, max-age=300"
+		case "server.cache_control.posts_search":
+			defVal = "public, max-age=60"
+		case "server.cache_control.songs":
+			defVal = "public, max-age=600"
+		case "server.cache_control.songs_search":
+			defVal = "public, max-age=60"
+		case "server.cache_control.charts":
+			defVal = "public, max-age=3600"
+		case "server.cache_control.assets_manifest":
+			defVal = "no-cache"
+		case "server.auth.enabled":
+			defVal = false
+		case "server.auth.keys":
+			defVal = []string{}
+		case "server.auth.window":
+			defVal = 60
+		case "server.auth.posts.require_key":
+			defVal = false
+		case "server.auth.posts.key_limit":
+			defVal = 600
+		case "server.auth.posts.ip_limit":
+			defVal = 60
+		case "server.auth.songs.require_key":
+			defVal = false
+		case "server.auth.songs.key_limit":
+			defVal = 600
+		case "server.auth.songs.ip_limit":
+			defVal = 60
+		case "server.auth.charts.require_key":
+			defVal = false
+		case "server.auth.charts.key_limit":
+			defVal = 600
+		case "server.auth.charts.ip_limit":
+			defVal = 60
+		case "server.auth.assets.require_key":
+			defVal = false
+		case "server.auth.assets.key_limit":
+			defVal = 0
+		case "server.auth.assets.ip_limit":
+			defVal = 0
+		case "assets.backend":
+			defVal = "filesystem"
+		case "assets.path":
+			defVal = "data/assets/"
+		case "assets.redirect":
+			defVal = false
+		case "assets.presign_expiry":
+			defVal = 3600
+		case "assets.s3.endpoint":
+			defVal = ""
+		case "assets.s3.access_key":
+			defVal = ""
+		case "assets.s3.secret_key":
+			defVal = ""
+		case "assets.s3.bucket":
+			defVal = ""
+		case "assets.s3.region":
+			defVal = ""
+		case "assets.s3.use_ssl":
+			defVal = false
+		case "assets.s3.prefix":
+			defVal = ""
+		case "assets.image.sizes":
+			defVal = []int{64, 128, 256, 512}
+		case "assets.image.webp_command":
+			defVal = []string{"cwebp", "-quiet", "-q", "80", "{input}", "-o", "{output}"}
+		case "assets.image.avif_command":
+			defVal = []string{"avifenc", "-q", "60", "{input}", "{output}"}
+		case "assets.image.encode_timeout":
+			defVal = 10
+		case "assets.audio.ffmpeg":
+			defVal = "ffmpeg"
+		case "assets.audio.opus_bitrate":
+			defVal = "96k"
+		case "assets.audio.preview_offset":
+			defVal = 60
+		case "assets.audio.preview_duration":
+			defVal = 30
+		case "assets.audio.encode_timeout":
+			defVal = 120
+		case "assets.mirror.enabled":
+			defVal = false
+		case "assets.mirror.base_url":
+			defVal = ""
+		case "assets.mirror.max_audio_bytes":
+			defVal = 20 << 20
+		case "assets.mirror.max_cover_bytes":
+			defVal = 5 << 20
+		case "assets.mirror.timeout":
+			defVal = 30
+		case "cache.path":
+			defVal = "data/cache/"
+		case "memory_cache.assets_max_bytes":
+			defVal = 64 << 20
+		case "memory_cache.cache_max_bytes":
+			defVal = 8 << 20
+		case "memory_cache.max_entry_bytes":
+			defVal = 2 << 20
+		case "memory_cache.cache_audio":
+			defVal = false
+		}
+		if defVal == nil {
+			continue
 		}
-		viper.Set(path, defVal)
+		v.Set(path, defVal)
 	}
 }
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"reflect"
+)
+
+// ChangedKeys 比较两份配置，返回取值不同的配置项路径，如 log.level
+func ChangedKeys(old, new *Config) []string {
+	var keys []string
+	diffStruct(reflect.ValueOf(*old), reflect.ValueOf(*new), "", &keys)
+	return keys
+}
+
+func diffStruct(old, new reflect.Value, prefix string, keys *[]string) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		oldField, newField := old.Field(i), new.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(oldField, newField, key+".", keys)
+			continue
+		}
+		if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			*keys = append(*keys, key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix 环境变量前缀
+const envPrefix = "ANON_DATABASE_"
+
+// configKey 配置项路径及其类型
+type configKey struct {
+	path string
+	kind reflect.Kind
+}
+
+// configKeys 根据 Config 结构体获取全部配置项路径
+func configKeys() []string {
+	fields := configFields(reflect.TypeOf(Config{}), "")
+	keys := make([]string, len(fields))
+	for i, field := range fields {
+		keys[i] = field.path
+	}
+	return keys
+}
+
+func configFields(t reflect.Type, prefix string) []configKey {
+	var fields []configKey
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, configFields(field.Type, prefix+tag+".")...)
+			continue
+		}
+		fields = append(fields, configKey{path: prefix + tag, kind: field.Type.Kind()})
+	}
+	return fields
+}
+
+// envKey 获取配置项对应的环境变量名，如 log.file.max_size 对应 ANON_DATABASE_LOG_FILE_MAX_SIZE
+func envKey(path string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// parseEnvList 解析列表类型的环境变量，支持 JSON 数组或逗号分隔
+func parseEnvList(val string) ([]any, error) {
+	if strings.HasPrefix(strings.TrimSpace(val), "[") {
+		var list []any
+		if err := json.Unmarshal([]byte(val), &list); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	var list []any
+	for item := range strings.SplitSeq(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// lookupEnv 读取配置项的环境变量，{KEY}_FILE 表示从文件读取取值，适用于挂载的密钥
+func lookupEnv(path string) (string, bool, error) {
+	key := envKey(path)
+	val := os.Getenv(key)
+	file := os.Getenv(key + "_FILE")
+	switch {
+	case val != "" && file != "":
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", key, key)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s_FILE: %w", key, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	case val != "":
+		return val, true, nil
+	}
+	return "", false, nil
+}
+
+// applyEnvOverrides 使用环境变量覆盖配置，优先级高于配置文件
+func applyEnvOverrides() error {
+	for _, field := range configFields(reflect.TypeOf(Config{}), "") {
+		val, ok, err := lookupEnv(field.path)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if field.kind == reflect.Slice {
+			list, err := parseEnvList(val)
+			if err != nil {
+				return fmt.Errorf("invalid list in %s: %w", envKey(field.path), err)
+			}
+			viper.Set(field.path, list)
+			continue
+		}
+		viper.Set(field.path, val)
+	}
+	return nil
+}
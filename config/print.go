@@ -0,0 +1,87 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// redactedValue 替换敏感配置项的占位内容
+const redactedValue = "******"
+
+// Print 以 YAML 格式输出配置，敏感配置项以占位内容代替
+func Print(w io.Writer, c *Config) error {
+	node, err := configNode(reflect.ValueOf(*c))
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// configNode 按结构体字段顺序生成 YAML 节点
+func configNode(v reflect.Value) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		value := &yaml.Node{}
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			child, err := configNode(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			value = child
+		case field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.Slice:
+			redacted := make([]string, v.Field(i).Len())
+			for j := range redacted {
+				redacted[j] = redactEntry(v.Field(i).Index(j).String())
+			}
+			if err := value.Encode(redacted); err != nil {
+				return nil, err
+			}
+		case field.Tag.Get("secret") == "true":
+			if err := value.Encode(redact(v.Field(i).String())); err != nil {
+				return nil, err
+			}
+		default:
+			if err := value.Encode(v.Field(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag}, value)
+	}
+	return node, nil
+}
+
+// redact 隐藏敏感内容，链接只隐藏其中的密码
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Redacted()
+	}
+	return redactedValue
+}
+
+// redactEntry 隐藏 name:key 形式条目中的密钥部分
+func redactEntry(s string) string {
+	if name, _, ok := strings.Cut(s, ":"); ok {
+		return name + ":" + redactedValue
+	}
+	return redact(s)
+}
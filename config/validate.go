@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// validator 收集全部校验错误
+type validator struct {
+	errs []error
+}
+
+func (v *validator) addf(key, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) positive(key string, value int64) {
+	if value <= 0 {
+		v.addf(key, "must be positive, got %d", value)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int64) {
+	if value < 0 {
+		v.addf(key, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.addf(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+func (v *validator) httpURL(key, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(key, "must be an http or https url, got %q", value)
+	}
+}
+
+func (v *validator) corsPolicy(key string, policy CORSPolicyConfig) {
+	for i, origin := range policy.AllowOrigins {
+		if origin == "*" {
+			// 允许携带凭据时不能放行任意来源
+			if policy.AllowCredentials {
+				v.addf(key+".allow_origins", "must not contain * when allow_credentials is enabled")
+			}
+			continue
+		}
+		if u, err := url.Parse(strings.Replace(origin, "*.", "", 1)); err != nil || u.Scheme == "" || u.Host == "" || strings.Count(origin, "*") > 1 {
+			v.addf(fmt.Sprintf("%s.allow_origins[%d]", key, i), "must be *, an origin like https://example.com or https://*.example.com, got %q", origin)
+		}
+	}
+	v.nonNegative(key+".max_age", int64(policy.MaxAge))
+}
+
+// normalize 统一枚举类配置项的大小写与空白，使校验与使用方看到相同的取值
+func (c *Config) normalize() {
+	for _, value := range []*string{&c.Log.Level, &c.Log.Format, &c.Assets.Backend} {
+		*value = strings.ToLower(strings.TrimSpace(*value))
+	}
+}
+
+// Validate 校验配置取值，一次性返回全部不合法的配置项
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if _, err := connstring.ParseAndValidate(c.Mongo.URI); err != nil {
+		v.addf("mongo.uri", "%v", err)
+	}
+
+	v.oneOf("log.level", c.Log.Level, "fatal", "error", "warn", "info", "debug", "trace")
+	v.oneOf("log.format", c.Log.Format, "text", "json")
+	v.nonNegative("log.file.max_size", int64(c.Log.File.MaxSize))
+	v.nonNegative("log.file.max_backups", int64(c.Log.File.MaxBackups))
+	v.nonNegative("log.file.max_age", int64(c.Log.File.MaxAge))
+
+	v.positive("api.timeout", int64(c.API.Timeout))
+	v.nonNegative("api.retry", int64(c.API.Retry))
+	v.nonNegative("api.gap", int64(c.API.Gap))
+	v.positive("api.update_interval", int64(c.API.UpdateInterval))
+	v.httpURL("api.base_url", c.API.BaseURL)
+	if c.API.Proxy != "" {
+		if u, err := url.Parse(c.API.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			v.addf("api.proxy", "must be a proxy url, got %q", c.API.Proxy)
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		v.addf("server.port", "must be a port number between 1 and 65535, got %q", c.Server.Port)
+	}
+	v.nonNegative("server.shutdown_timeout", int64(c.Server.ShutdownTimeout))
+	for _, policy := range []struct {
+		name string
+		conf CORSPolicyConfig
+	}{
+		{"default", c.Server.CORS.Default},
+		{"posts", c.Server.CORS.Posts},
+		{"songs", c.Server.CORS.Songs},
+		{"charts", c.Server.CORS.Charts},
+		{"assets", c.Server.CORS.Assets},
+	} {
+		v.corsPolicy("server.cors."+policy.name, policy.conf)
+	}
+	v.positive("server.auth.window", int64(c.Server.Auth.Window))
+	for i, entry := range c.Server.Auth.Keys {
+		if name, key, ok := strings.Cut(entry, ":"); !ok || name == "" || key == "" {
+			v.addf(fmt.Sprintf("server.auth.keys[%d]", i), "must be in name:key form")
+		}
+	}
+	for _, route := range []struct {
+		name string
+		conf RouteAuthConfig
+	}{
+		{"posts", c.Server.Auth.Posts},
+		{"songs", c.Server.Auth.Songs},
+		{"charts", c.Server.Auth.Charts},
+		{"assets", c.Server.Auth.Assets},
+	} {
+		v.nonNegative("server.auth."+route.name+".key_limit", int64(route.conf.KeyLimit))
+		v.nonNegative("server.auth."+route.name+".ip_limit", int64(route.conf.IPLimit))
+	}
+
+	v.oneOf("assets.backend", c.Assets.Backend, "filesystem", "s3")
+	if c.Assets.Backend == "s3" {
+		if c.Assets.S3.Endpoint == "" {
+			v.addf("assets.s3.endpoint", "is required for s3 backend")
+		}
+		if c.Assets.S3.Bucket == "" {
+			v.addf("assets.s3.bucket", "is required for s3 backend")
+		}
+	}
+	v.nonNegative("assets.presign_expiry", int64(c.Assets.PresignExpiry))
+	for i, size := range c.Assets.Image.Sizes {
+		v.positive(fmt.Sprintf("assets.image.sizes[%d]", i), int64(size))
+	}
+	v.positive("assets.image.encode_timeout", int64(c.Assets.Image.EncodeTimeout))
+	v.nonNegative("assets.audio.preview_offset", int64(c.Assets.Audio.PreviewOffset))
+	v.positive("assets.audio.preview_duration", int64(c.Assets.Audio.PreviewDuration))
+	v.positive("assets.audio.encode_timeout", int64(c.Assets.Audio.EncodeTimeout))
+	// 镜像链接前缀可以是仅含路径的相对地址
+	if _, err := url.Parse(c.Assets.Mirror.BaseURL); err != nil {
+		v.addf("assets.mirror.base_url", "%v", err)
+	}
+	v.nonNegative("assets.mirror.max_audio_bytes", c.Assets.Mirror.MaxAudioBytes)
+	v.nonNegative("assets.mirror.max_cover_bytes", c.Assets.Mirror.MaxCoverBytes)
+	v.positive("assets.mirror.timeout", int64(c.Assets.Mirror.Timeout))
+
+	v.nonNegative("memory_cache.assets_max_bytes", c.MemoryCache.AssetsMaxBytes)
+	v.nonNegative("memory_cache.cache_max_bytes", c.MemoryCache.CacheMaxBytes)
+	v.nonNegative("memory_cache.max_entry_bytes", c.MemoryCache.MaxEntryBytes)
+
+	return errors.Join(v.errs...)
+}
@@ -0,0 +1,213 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/endpoints"
+
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+)
+
+// assetsUpdateInterval 额外资源的检查间隔，列表数据较大，不必每次定时更新都拉取
+const assetsUpdateInterval = time.Hour
+
+// assetsTask 单个待下载的资源
+type assetsTask struct {
+	name     string // 资源存储名称
+	endpoint string // Bestdori 上的资源路径
+}
+
+// assetsCollector 从上游列表数据中收集某一类别的资源
+type assetsCollector struct {
+	category files.AssetsCategory
+	collect  func(source Source) ([]assetsTask, error)
+}
+
+var assetsCollectors = []assetsCollector{
+	{category: files.CategoryBandLogo, collect: collectBandLogos},
+	{category: files.CategoryCharaIcon, collect: collectCharaIcons},
+	{category: files.CategoryEventBanner, collect: collectEventBanners},
+	{category: files.CategoryCardThumb, collect: collectCardThumbs},
+}
+
+// defaultServerName 根据各服务器的发布时间确定资源所在服务器
+func defaultServerName(times []*string) dto.ServerName {
+	servers := []dto.ServerName{
+		dto.ServerNameJP,
+		dto.ServerNameEN,
+		dto.ServerNameTW,
+		dto.ServerNameCN,
+		dto.ServerNameKR,
+	}
+	for i, server := range servers {
+		if i < len(times) && times[i] != nil {
+			return server
+		}
+	}
+	return ""
+}
+
+// sortedIDs 将以字符串 ID 为键的映射转换为有序的 ID 列表
+func sortedIDs[V any](m map[string]V) []int {
+	ids := make([]int, 0, len(m))
+	for idStr := range m {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func collectBandLogos(source Source) ([]assetsTask, error) {
+	bands, err := source.GetBands()
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]assetsTask, 0, len(*bands))
+	for _, id := range sortedIDs(*bands) {
+		tasks = append(tasks, assetsTask{
+			name:     files.CategoryBandLogo.Path(fmt.Sprintf("%d.png", id)),
+			endpoint: endpoints.BandLogo(string(dto.ServerNameJP), id, "logoL"),
+		})
+	}
+	return tasks, nil
+}
+
+func collectCharaIcons(source Source) ([]assetsTask, error) {
+	characters, err := source.GetCharacters()
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]assetsTask, 0, len(*characters))
+	for _, id := range sortedIDs(*characters) {
+		tasks = append(tasks, assetsTask{
+			name:     files.CategoryCharaIcon.Path(fmt.Sprintf("%d.png", id)),
+			endpoint: endpoints.ResIconPng(endpoints.CharaIcon(id)),
+		})
+	}
+	return tasks, nil
+}
+
+func collectEventBanners(source Source) ([]assetsTask, error) {
+	events, err := source.GetEvents()
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]assetsTask, 0, len(*events))
+	for _, id := range sortedIDs(*events) {
+		info := (*events)[strconv.Itoa(id)]
+		server := defaultServerName(info.StartAt)
+		if server == "" || info.AssetBundleName == "" {
+			continue
+		}
+		tasks = append(tasks, assetsTask{
+			name:     files.CategoryEventBanner.Path(fmt.Sprintf("%d.png", id)),
+			endpoint: endpoints.EventBanner(string(server), info.AssetBundleName),
+		})
+	}
+	return tasks, nil
+}
+
+func collectCardThumbs(source Source) ([]assetsTask, error) {
+	cards, err := source.GetCards()
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]assetsTask, 0, len(*cards)*2)
+	for _, id := range sortedIDs(*cards) {
+		info := (*cards)[strconv.Itoa(id)]
+		server := defaultServerName(info.ReleasedAt)
+		if server == "" || info.ResourceSetName == "" {
+			continue
+		}
+		trains := []dto.CardTrain{dto.CardTrainNormal}
+		if info.Rarity >= dto.CardRarity3 {
+			trains = append(trains, dto.CardTrainAfterTraining)
+		}
+		for _, train := range trains {
+			tasks = append(tasks, assetsTask{
+				name:     files.CategoryCardThumb.Path(fmt.Sprintf("%d_%s.png", id, train)),
+				endpoint: endpoints.ThumbChara(string(server), id/50, info.ResourceSetName, string(train)),
+			})
+		}
+	}
+	return tasks, nil
+}
+
+// updateAssets 下载缺失的乐队 Logo、角色图标、活动横幅与卡牌缩略图
+//
+// force 为 false 时距离上次检查不足 assetsUpdateInterval 则跳过
+func (du *DataUpdater) updateAssets(force bool) error {
+	if err := du.ctx.Err(); err != nil {
+		return err
+	}
+	du.mu.Lock()
+	if !force && time.Since(du.lastAssetsUpdate) < assetsUpdateInterval {
+		du.mu.Unlock()
+		return nil
+	}
+	du.lastAssetsUpdate = time.Now()
+	du.mu.Unlock()
+
+	// 下载过程中只记录清单项，全部完成后一次写回
+	defer func() {
+		if err := files.FlushManifest(); err != nil {
+			log.Errorf("failed to save assets manifest: %v", err)
+		}
+	}()
+
+	for _, collector := range assetsCollectors {
+		if err := du.ctx.Err(); err != nil {
+			return err
+		}
+		tasks, err := collector.collect(du.currentSource())
+		if err != nil {
+			log.Errorf("failed to get %s list: %v", collector.category.Name, err)
+			continue
+		}
+		for _, task := range tasks {
+			if err := du.ctx.Err(); err != nil {
+				return err
+			}
+			du.ensureAssets(task)
+		}
+	}
+	return nil
+}
+
+// ensureAssets 资源不存在时下载
+//
+// 返回：是否进行了下载和下载失败时的错误信息
+func (du *DataUpdater) ensureAssets(task assetsTask) (bool, error) {
+	exists, err := files.AssetsExists(task.name)
+	if err != nil {
+		log.Errorf("failed to check assets %s: %v", task.name, err)
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	log.Infof("downloading missing assets %s", task.name)
+	if err := retry(func() error {
+		data, err := du.currentSource().GetAssetsFile(task.name, task.endpoint)
+		if err != nil {
+			return err
+		}
+		return files.SaveAssets(task.name, data)
+	}); err != nil {
+		log.Errorf("failed to update assets %s: %v", task.name, err)
+		return true, err
+	}
+	log.Infof("updated assets %s", task.name)
+	observeAssetsDownloaded(task.name)
+	return true, nil
+}
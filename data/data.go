@@ -4,62 +4,102 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	bestdoriapi "github.com/WindowsSov8forUs/bestdori-api-go"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori"
 	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
-	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/post"
 	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/songs"
 	"github.com/WindowsSov8forUs/bestdori-api-go/uniapi"
 
 	"anon-bestdori-database/config"
-	"anon-bestdori-database/database"
 	"anon-bestdori-database/files"
 	"anon-bestdori-database/pkg/log"
 )
 
 type DataUpdater struct {
-	bestdoriAPI   *uniapi.UniAPI
-	niconiAPI     *uniapi.UniAPI
-	db            *database.Database
-	conf          *config.Config
-	ctx           context.Context
-	postGapLimit  int
-	mu            sync.Mutex
-	updateRunning bool
-	updateDone    chan struct{}
+	source           Source
+	db               Store
+	conf             *config.Config
+	ctx              context.Context
+	cancel           context.CancelFunc
+	postGapLimit     int
+	updateInterval   int
+	settingsMu       sync.RWMutex // 保护可在运行中替换的 source、postGapLimit、updateInterval 与 mirror
+	mu               sync.Mutex
+	updateRunning    bool
+	updateDone       chan struct{}
+	lastAssetsUpdate time.Time
+	mirror           *postMirror
+	status           UpdaterStatus
 }
 
-var retryAttempts int
+var retryAttempts atomic.Int64
 
 func setRetryAttempts(n int) {
 	if n <= 0 {
-		retryAttempts = 1
+		retryAttempts.Store(1)
 		return
 	}
-	retryAttempts = n
+	retryAttempts.Store(int64(n))
 }
 
-func NewDataUpdater(db *database.Database, conf *config.Config, ctx context.Context) *DataUpdater {
+func NewDataUpdater(db Store, conf *config.Config, ctx context.Context) *DataUpdater {
+	return NewDataUpdaterWithSource(db, conf, ctx, NewSource(conf))
+}
+
+// NewSource 根据配置创建上游数据源
+//
+// 配置了 api.snapshot 时从本地快照读取，否则访问 Bestdori（或 api.base_url 指定的替身服务）
+func NewSource(conf *config.Config) Source {
+	if conf.API.Snapshot != "" {
+		log.Infof("using local snapshot %s as upstream source", conf.API.Snapshot)
+		return NewFixtureSource(conf.API.Snapshot)
+	}
+
 	bestdoriapi.RegisterLogger(log.GetLogger())
 
-	bestdoriAPI := bestdoriapi.NewBestdoriAPI(conf.API.Proxy, conf.API.Timeout, conf.API.Retry)
-	niconiAPI := bestdoriapi.NewNiconiAPI(conf.API.Proxy, conf.API.Timeout, conf.API.Retry)
+	baseURL := "https://bestdori.com"
+	if conf.API.BaseURL != "" {
+		log.Infof("using %s as upstream Bestdori server", conf.API.BaseURL)
+		baseURL = conf.API.BaseURL
+	}
+
+	// 与 bestdoriapi.NewBestdoriAPI 等价，但需要在其他响应中间件之前注册统计中间件
+	bestdoriAPI := uniapi.NewAPI(baseURL, conf.API.Proxy, conf.API.Timeout, conf.API.Retry)
+	bestdoriAPI.OnBeforeRequest(bestdori.OnBeforeRequestBestdori)
+	bestdoriAPI.OnAfterResponse(upstreamMetricsMiddleware("bestdori"))
+	bestdoriAPI.OnAfterResponse(bestdoriAPI.ContentTypeMiddleware())
+	bestdoriAPI.OnAfterResponse(bestdori.OnAfterResponseBestdori)
+
+	niconiAPI := uniapi.NewAPI("https://card.niconi.co.ni", conf.API.Proxy, conf.API.Timeout, conf.API.Retry)
+	niconiAPI.OnAfterResponse(upstreamMetricsMiddleware("niconi"))
+	niconiAPI.OnAfterResponse(bestdori.OnAfterResponseNiconi)
+	niconiAPI.OnAfterResponse(niconiAPI.ContentTypeMiddleware())
+
+	return NewBestdoriSource(bestdoriAPI, niconiAPI)
+}
 
+// NewDataUpdaterWithSource 使用指定的上游数据源创建 DataUpdater
+func NewDataUpdaterWithSource(db Store, conf *config.Config, ctx context.Context, source Source) *DataUpdater {
 	setRetryAttempts(conf.API.Retry)
 
+	ctx, cancel := context.WithCancel(ctx)
 	return &DataUpdater{
-		bestdoriAPI:  bestdoriAPI,
-		niconiAPI:    niconiAPI,
-		db:           db,
-		conf:         conf,
-		ctx:          ctx,
-		postGapLimit: conf.API.Gap,
+		source:         source,
+		db:             db,
+		conf:           conf,
+		ctx:            ctx,
+		cancel:         cancel,
+		postGapLimit:   conf.API.Gap,
+		updateInterval: conf.API.UpdateInterval,
+		mirror:         newPostMirror(conf),
 	}
 }
 
 func retry(fn func() error) error {
-	attempts := retryAttempts
+	attempts := int(retryAttempts.Load())
 	if attempts <= 0 {
 		attempts = 5
 	}
@@ -68,6 +108,7 @@ func retry(fn func() error) error {
 		if err := fn(); err != nil {
 			lastErr = err
 			if isResponseStatusError(err) && i < attempts-1 {
+				syncRetries.Inc()
 				time.Sleep(3 * time.Second)
 				continue
 			}
@@ -83,50 +124,52 @@ func isResponseStatusError(err error) bool {
 	return ok
 }
 
-func getSong(api *uniapi.UniAPI, id int) (*songs.Song, error) {
-	var song *songs.Song
+func getSong(source Source, id int) (*songs.Song, error) {
+	var info *dto.SongInfo
 	err := retry(func() error {
 		var err error
-		song, err = songs.GetSong(api, id)
+		info, err = source.GetSong(id)
 		return err
 	})
 	if err != nil {
 		return nil, err
 	}
-	return song, nil
+	return &songs.Song{Id: id, Info: info}, nil
 }
 
-func downloadMusicJacket(jacket songs.Jacket) error {
-	jacketName := "musicjacket/" + jacket.JacketImage + ".png"
-	data, err := jacket.Bytes()
+func downloadMusicJacket(source Source, jacket songs.Jacket) error {
+	jacketName := files.CategoryMusicJacket.Path(jacket.JacketImage + ".png")
+	data, err := source.GetJacket(jacket)
 	if err != nil {
 		return err
 	}
-	err = files.SaveAssets(jacketName, *data)
+	err = files.SaveAssets(jacketName, data)
 	if err != nil {
 		return err
 	}
+	observeAssetsDownloaded(jacketName)
 	return nil
 }
 
-func downloadBGM(song *songs.Song) error {
-	data, err := song.GetBGM()
+func downloadBGM(source Source, song *songs.Song) error {
+	data, err := source.GetBGM(song)
 	if err != nil {
 		return err
 	}
-	bgmName := fmt.Sprintf("sound/bgm%03d.mp3", song.Id)
-	err = files.SaveAssets(bgmName, *data)
+	bgmName := files.CategorySound.Path(fmt.Sprintf("bgm%03d.mp3", song.Id))
+	err = files.SaveAssets(bgmName, data)
 	if err != nil {
 		return err
 	}
+	observeAssetsDownloaded(bgmName)
 	return nil
 }
 
-func getChart(song *songs.Song, diff dto.ChartDifficultyName) (*dto.Chart, error) {
+func getChart(source Source, song *songs.Song, diff dto.ChartDifficultyName) (*dto.Chart, error) {
 	var chart *dto.Chart
 	err := retry(func() error {
 		var err error
-		chart, err = song.GetChart(diff)
+		chart, err = source.GetChart(song.Id, diff)
 		return err
 	})
 	if err != nil {
@@ -135,11 +178,11 @@ func getChart(song *songs.Song, diff dto.ChartDifficultyName) (*dto.Chart, error
 	return chart, nil
 }
 
-func getPost(bdAPI, nicoAPI *uniapi.UniAPI, id int) (*post.Post, error) {
-	var p *post.Post
+func getPost(source Source, id int) (*dto.PostInfo, error) {
+	var p *dto.PostInfo
 	err := retry(func() error {
 		var err error
-		p, err = post.GetPost(bdAPI, nicoAPI, id)
+		p, err = source.GetPost(id)
 		return err
 	})
 	if err != nil {
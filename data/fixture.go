@@ -0,0 +1,143 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/songs"
+)
+
+// FixtureSource 从磁盘上录制好的 JSON 与资源文件读取数据
+//
+// 目录结构：
+//
+//	songs/all.8.json
+//	songs/{id}.json
+//	charts/{id}/{diff}.json
+//	posts/list.json              按时间升序的全部谱面帖子
+//	posts/{id}.json              帖子详情（/api/post/details 的响应）
+//	bands/main.1.json
+//	characters/main.1.json
+//	events/all.3.json
+//	cards/all.5.json
+//	assets/{name}                资源文件，与资源存储名称一致，例如：
+//	assets/musicjacket/{jacketImage}.png
+//	assets/sound/bgm{id:03d}.mp3
+//
+// 缺失的文件一律视为上游不存在该数据，返回 *bestdori.NotExistError
+type FixtureSource struct {
+	dir string
+}
+
+func NewFixtureSource(dir string) *FixtureSource {
+	return &FixtureSource{dir: dir}
+}
+
+func (s *FixtureSource) path(elem ...string) string {
+	return filepath.Join(append([]string{s.dir}, elem...)...)
+}
+
+func (s *FixtureSource) readFile(target string, elem ...string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(elem...))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &bestdori.NotExistError{Target: target}
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func readFixtureJSON[T any](s *FixtureSource, target string, elem ...string) (*T, error) {
+	data, err := s.readFile(target, elem...)
+	if err != nil {
+		return nil, err
+	}
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", filepath.Join(elem...), err)
+	}
+	return &result, nil
+}
+
+func (s *FixtureSource) GetAll8() (*dto.SongsAll8, error) {
+	return readFixtureJSON[dto.SongsAll8](s, "songs all.8", "songs", "all.8.json")
+}
+
+func (s *FixtureSource) GetSong(id int) (*dto.SongInfo, error) {
+	return readFixtureJSON[dto.SongInfo](s, "song "+strconv.Itoa(id), "songs", strconv.Itoa(id)+".json")
+}
+
+func (s *FixtureSource) GetChart(id int, diff dto.ChartDifficultyName) (*dto.Chart, error) {
+	return readFixtureJSON[dto.Chart](
+		s,
+		string(diff)+" chart of song "+strconv.Itoa(id),
+		"charts", strconv.Itoa(id), string(diff)+".json",
+	)
+}
+
+func (s *FixtureSource) GetJacket(jacket songs.Jacket) ([]byte, error) {
+	return s.readFile("jacket "+jacket.JacketImage, "assets", "musicjacket", jacket.JacketImage+".png")
+}
+
+func (s *FixtureSource) GetBGM(song *songs.Song) ([]byte, error) {
+	return s.readFile(
+		"BGM of song "+strconv.Itoa(song.Id),
+		"assets", "sound", fmt.Sprintf("bgm%03d.mp3", song.Id),
+	)
+}
+
+func (s *FixtureSource) GetPostList(offset, limit int) (*dto.PostList, error) {
+	list, err := readFixtureJSON[dto.PostList](s, "post list", "posts", "list.json")
+	if err != nil {
+		return nil, err
+	}
+	posts := list.Posts
+	if offset >= len(posts) {
+		posts = nil
+	} else {
+		posts = posts[offset:]
+		if limit > 0 && limit < len(posts) {
+			posts = posts[:limit]
+		}
+	}
+	return &dto.PostList{
+		Result: true,
+		Posts:  posts,
+		Count:  len(list.Posts),
+	}, nil
+}
+
+func (s *FixtureSource) GetPost(id int) (*dto.PostInfo, error) {
+	detail, err := readFixtureJSON[dto.PostDetail](s, "post "+strconv.Itoa(id), "posts", strconv.Itoa(id)+".json")
+	if err != nil {
+		return nil, err
+	}
+	return &detail.Post, nil
+}
+
+func (s *FixtureSource) GetBands() (*dto.BandsMain1, error) {
+	return readFixtureJSON[dto.BandsMain1](s, "bands main.1", "bands", "main.1.json")
+}
+
+func (s *FixtureSource) GetCharacters() (*dto.CharactersMain1, error) {
+	return readFixtureJSON[dto.CharactersMain1](s, "characters main.1", "characters", "main.1.json")
+}
+
+func (s *FixtureSource) GetEvents() (*dto.EventsAll3, error) {
+	return readFixtureJSON[dto.EventsAll3](s, "events all.3", "events", "all.3.json")
+}
+
+func (s *FixtureSource) GetCards() (*dto.CardsAll5, error) {
+	return readFixtureJSON[dto.CardsAll5](s, "cards all.5", "cards", "all.5.json")
+}
+
+func (s *FixtureSource) GetAssetsFile(name, _ string) ([]byte, error) {
+	return s.readFile("assets "+name, append([]string{"assets"}, strings.Split(name, "/")...)...)
+}
@@ -5,12 +5,15 @@ import (
 	"time"
 
 	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
-	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/post"
 
 	"anon-bestdori-database/pkg/log"
 )
 
-func (du *DataUpdater) Init() error {
+func (du *DataUpdater) Init() (err error) {
+	defer func() {
+		du.recordUpdateResult(err)
+	}()
+
 	if err := du.initSongs(); err != nil {
 		log.Errorf("failed to initialize songs data: %v", err)
 		return err
@@ -21,6 +24,11 @@ func (du *DataUpdater) Init() error {
 		return err
 	}
 
+	if err := du.updateAssets(true); err != nil {
+		log.Errorf("failed to initialize assets: %v", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -28,22 +36,11 @@ func (du *DataUpdater) initSongs() error {
 	return du.updateSongs()
 }
 
-func getPostList(du *DataUpdater, offset, limit int) (*dto.PostList, error) {
+func getPostList(source Source, offset, limit int) (*dto.PostList, error) {
 	var list *dto.PostList
 	err := retry(func() error {
 		var err error
-		list, err = post.GetList(
-			du.bestdoriAPI,
-			"",
-			false,
-			"SELF_POST",
-			"chart",
-			nil,
-			"",
-			post.OrderTimeAsc,
-			limit,
-			offset,
-		)
+		list, err = source.GetPostList(offset, limit)
 		return err
 	})
 	if err != nil {
@@ -58,7 +55,7 @@ func (du *DataUpdater) initPosts() error {
 	limit := 50
 
 	for {
-		list, err := getPostList(du, offset, limit)
+		list, err := getPostList(du.currentSource(), offset, limit)
 		if err != nil {
 			log.Errorf("failed to get post list with offset %d: %v", offset, err)
 			return err
@@ -77,7 +74,7 @@ func (du *DataUpdater) initPosts() error {
 		go func(ids []int) {
 			defer wg.Done()
 			for _, pid := range ids {
-				existing, err := du.db.GetPostByID(du.ctx, pid)
+				existing, err := du.db.GetPostByID(du.dbCtx(), pid)
 				if err != nil {
 					log.Errorf("failed to check existing post %d: %v", pid, err)
 					continue
@@ -89,11 +86,11 @@ func (du *DataUpdater) initPosts() error {
 
 				log.Infof("getting info of post %d ...", pid)
 				err = retry(func() error {
-					postInst, err := getPost(du.bestdoriAPI, du.niconiAPI, pid)
+					postInfo, err := getPost(du.currentSource(), pid)
 					if err != nil {
 						return err
 					}
-					return du.db.UpsertPost(du.ctx, pid, postInst.Info)
+					return du.db.UpsertPost(du.dbCtx(), pid, postInfo)
 				})
 				if err != nil {
 					log.Errorf("failed to get info of post %d: %v", pid, err)
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	syncSongsUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "sync",
+		Name:      "songs_updated_total",
+		Help:      "Songs written to the database by the updater.",
+	})
+	syncPostsUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "sync",
+		Name:      "posts_updated_total",
+		Help:      "Chart posts written to the database by the updater.",
+	})
+	syncAssetsDownloaded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "sync",
+		Name:      "assets_downloaded_total",
+		Help:      "Assets downloaded by the updater by category.",
+	}, []string{"category"})
+	syncFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "sync",
+		Name:      "failures_total",
+		Help:      "Updater failures by task.",
+	}, []string{"task"})
+	syncRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "sync",
+		Name:      "retries_total",
+		Help:      "Upstream calls retried by the updater.",
+	})
+	syncLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "anon",
+		Subsystem: "sync",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful initialization or update.",
+	})
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "upstream",
+		Name:      "requests_total",
+		Help:      "Upstream HTTP responses by API and status code.",
+	}, []string{"api", "status"})
+)
+
+// upstreamMetricsMiddleware 统计上游响应状态码，需在其他响应中间件之前注册，以免错误响应被提前中断
+func upstreamMetricsMiddleware(api string) resty.ResponseMiddleware {
+	return func(_ *resty.Client, resp *resty.Response) error {
+		upstreamRequests.WithLabelValues(api, strconv.Itoa(resp.StatusCode())).Inc()
+		return nil
+	}
+}
+
+// observeAssetsDownloaded 统计下载的资源
+func observeAssetsDownloaded(name string) {
+	category, _, _ := strings.Cut(name, "/")
+	syncAssetsDownloaded.WithLabelValues(category).Inc()
+}
@@ -0,0 +1,285 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/database"
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+)
+
+// 镜像允许的资源类型及其对应的文件后缀
+var (
+	mirrorAudioTypes = map[string]string{
+		"audio/mpeg":      ".mp3",
+		"audio/mp3":       ".mp3",
+		"audio/ogg":       ".ogg",
+		"application/ogg": ".ogg",
+		"audio/wav":       ".wav",
+		"audio/wave":      ".wav",
+		"audio/x-wav":     ".wav",
+		"audio/mp4":       ".m4a",
+		"audio/x-m4a":     ".m4a",
+		"audio/aac":       ".aac",
+		"audio/flac":      ".flac",
+		"audio/x-flac":    ".flac",
+	}
+	mirrorImageTypes = map[string]string{
+		"image/png":  ".png",
+		"image/jpeg": ".jpg",
+		"image/webp": ".webp",
+		"image/gif":  ".gif",
+	}
+)
+
+// errForbiddenAddress 镜像目标为内网、回环、链路本地或未指定地址
+var errForbiddenAddress = errors.New("forbidden mirror target address")
+
+// cgnatPrefix 运营商级 NAT 共享地址段
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbiddenIP 检查镜像是否不允许访问该地址
+func forbiddenIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsValid() ||
+		cgnatPrefix.Contains(ip) ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// mirrorDialControl 在建立连接前检查解析后的地址，重定向与 DNS 重绑定同样经过此检查
+func mirrorDialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || forbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, host)
+	}
+	return nil
+}
+
+// checkMirrorHost 解析主机名并检查全部地址
+//
+// 使用代理时连接由代理建立，只能在发出请求前检查
+func checkMirrorHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", errForbiddenAddress, host, addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// postMirror 将社区谱面自定义歌曲的外部音频与封面镜像到资源存储
+type postMirror struct {
+	client        *http.Client
+	viaProxy      bool
+	maxAudioBytes int64
+	maxCoverBytes int64
+}
+
+// newPostMirror 根据配置创建镜像器，未启用时返回 nil
+func newPostMirror(conf *config.Config) *postMirror {
+	mirrorConf := conf.Assets.Mirror
+	if !mirrorConf.Enabled {
+		return nil
+	}
+
+	m := &postMirror{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 不使用环境变量中的代理，确保直连时每个连接目标都经过地址检查
+	transport.Proxy = nil
+	if conf.API.Proxy != "" {
+		if proxyURL, err := url.Parse(conf.API.Proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+			m.viaProxy = true
+		} else {
+			log.Errorf("invalid proxy %s for post mirror: %v", conf.API.Proxy, err)
+		}
+	}
+	if !m.viaProxy {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   mirrorDialControl,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	m.client = &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(mirrorConf.Timeout) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return m.checkURL(req.Context(), req.URL)
+		},
+	}
+	m.maxAudioBytes = mirrorConf.MaxAudioBytes
+	m.maxCoverBytes = mirrorConf.MaxCoverBytes
+	return m
+}
+
+// checkURL 检查镜像链接的协议，使用代理时同时检查目标主机解析后的地址
+func (m *postMirror) checkURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if m.viaProxy {
+		return checkMirrorHost(ctx, u.Hostname())
+	}
+	return nil
+}
+
+// download 下载外部资源，检查大小与类型
+//
+// 返回：资源数据和对应的文件后缀
+func (m *postMirror) download(rawURL string, maxBytes int64, allowed map[string]string) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := m.checkURL(context.Background(), u); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := m.client.Get(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("file size %d exceeds limit %d", resp.ContentLength, maxBytes)
+	}
+
+	reader := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("file size exceeds limit %d", maxBytes)
+	}
+
+	// 优先使用响应头声明的类型，无法识别时根据内容嗅探
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if ext, ok := allowed[strings.ToLower(mediaType)]; ok {
+		return data, ext, nil
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if ext, ok := allowed[sniffed]; ok {
+		return data, ext, nil
+	}
+	return nil, "", fmt.Errorf("content type %q not allowed", mediaType)
+}
+
+// mirror 镜像单个外部资源，返回资源名称
+func (m *postMirror) mirror(id int, kind, rawURL string, maxBytes int64, allowed map[string]string) (string, error) {
+	data, ext, err := m.download(rawURL, maxBytes, allowed)
+	if err != nil {
+		return "", err
+	}
+	name := files.CategoryPosts.Path(strconv.Itoa(id) + "/" + kind + ext)
+	if err := files.SaveAssets(name, data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// mirroredAssetsExists 检查镜像资源是否仍存在，优先使用资源清单
+func mirroredAssetsExists(name string) bool {
+	if _, ok := files.GetManifestEntry(name); ok {
+		return true
+	}
+	exists, err := files.AssetsExists(name)
+	return err == nil && exists
+}
+
+// mirrorPostAssets 镜像帖子中自定义歌曲的音频与封面并保存镜像记录
+//
+// 帖子中保留上游链接，由接口在响应时替换为镜像链接；上游链接未变化且镜像资源仍存在时不重新下载
+func (du *DataUpdater) mirrorPostAssets(id int, info *dto.PostInfo) {
+	mirror := du.currentMirror()
+	if mirror == nil || info.Song == nil || info.Song.Type != dto.PostSongTypeCustom {
+		return
+	}
+
+	record, err := du.db.GetPostMirror(du.dbCtx(), id)
+	if err != nil {
+		log.Errorf("failed to get mirror record of post %d: %v", id, err)
+		return
+	}
+	if record == nil {
+		record = &database.PostMirror{PostID: id}
+	}
+	if record.Assets == nil {
+		record.Assets = map[string]database.MirroredAssets{}
+	}
+
+	targets := []struct {
+		kind     string
+		url      string
+		maxBytes int64
+		allowed  map[string]string
+	}{
+		{"audio", info.Song.Audio, mirror.maxAudioBytes, mirrorAudioTypes},
+		{"cover", info.Song.Cover, mirror.maxCoverBytes, mirrorImageTypes},
+	}
+	changed := false
+	for _, target := range targets {
+		if target.url == "" {
+			continue
+		}
+		if mirrored, ok := record.Assets[target.kind]; ok && mirrored.Source == target.url && mirroredAssetsExists(mirrored.Name) {
+			continue
+		}
+		name, err := mirror.mirror(id, target.kind, target.url, target.maxBytes, target.allowed)
+		if err != nil {
+			log.Warnf("failed to mirror %s of post %d from %s: %v", target.kind, id, target.url, err)
+			continue
+		}
+		log.Infof("mirrored %s of post %d to %s", target.kind, id, name)
+		record.Assets[target.kind] = database.MirroredAssets{Source: target.url, Name: name}
+		changed = true
+	}
+
+	if changed {
+		if err := du.db.UpsertPostMirror(du.dbCtx(), record); err != nil {
+			log.Errorf("failed to save mirror record of post %d: %v", id, err)
+		}
+	}
+}
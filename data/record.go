@@ -0,0 +1,300 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/songs"
+
+	"anon-bestdori-database/pkg/log"
+)
+
+// RecordingSource 包装另一个数据源，并将所有成功的响应按 FixtureSource 的目录结构写入磁盘
+type RecordingSource struct {
+	source Source
+	dir    string
+	mu     sync.Mutex
+	posts  []dto.PostListPost
+}
+
+func NewRecordingSource(source Source, dir string) *RecordingSource {
+	return &RecordingSource{
+		source: source,
+		dir:    dir,
+	}
+}
+
+func (s *RecordingSource) writeFile(data []byte, elem ...string) error {
+	filePath := filepath.Join(append([]string{s.dir}, elem...)...)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+func (s *RecordingSource) writeJSON(v any, elem ...string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.writeFile(data, elem...)
+}
+
+func (s *RecordingSource) record(err error, elem ...string) {
+	if err != nil {
+		log.Errorf("failed to record snapshot file %s: %v", filepath.Join(elem...), err)
+	}
+}
+
+func (s *RecordingSource) GetAll8() (*dto.SongsAll8, error) {
+	all8, err := s.source.GetAll8()
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"songs", "all.8.json"}
+	s.record(s.writeJSON(all8, elem...), elem...)
+	return all8, nil
+}
+
+func (s *RecordingSource) GetSong(id int) (*dto.SongInfo, error) {
+	info, err := s.source.GetSong(id)
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"songs", strconv.Itoa(id) + ".json"}
+	s.record(s.writeJSON(info, elem...), elem...)
+	return info, nil
+}
+
+func (s *RecordingSource) GetChart(id int, diff dto.ChartDifficultyName) (*dto.Chart, error) {
+	chart, err := s.source.GetChart(id, diff)
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"charts", strconv.Itoa(id), string(diff) + ".json"}
+	s.record(s.writeJSON(chart, elem...), elem...)
+	return chart, nil
+}
+
+func (s *RecordingSource) GetJacket(jacket songs.Jacket) ([]byte, error) {
+	data, err := s.source.GetJacket(jacket)
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"assets", "musicjacket", jacket.JacketImage + ".png"}
+	s.record(s.writeFile(data, elem...), elem...)
+	return data, nil
+}
+
+func (s *RecordingSource) GetBGM(song *songs.Song) ([]byte, error) {
+	data, err := s.source.GetBGM(song)
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"assets", "sound", fmt.Sprintf("bgm%03d.mp3", song.Id)}
+	s.record(s.writeFile(data, elem...), elem...)
+	return data, nil
+}
+
+// GetPostList 录制时在内存中合并各页，由 flushPostList 一次写入 posts/list.json
+func (s *RecordingSource) GetPostList(offset, limit int) (*dto.PostList, error) {
+	list, err := s.source.GetPostList(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if end := offset + len(list.Posts); end > len(s.posts) {
+		s.posts = slices.Grow(s.posts, end-len(s.posts))[:end]
+	}
+	copy(s.posts[offset:], list.Posts)
+	return list, nil
+}
+
+// flushPostList 将已录制的帖子列表写入 posts/list.json
+func (s *RecordingSource) flushPostList() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.posts) == 0 {
+		return
+	}
+	elem := []string{"posts", "list.json"}
+	s.record(s.writeJSON(dto.PostList{
+		Result: true,
+		Posts:  s.posts,
+		Count:  len(s.posts),
+	}, elem...), elem...)
+}
+
+func (s *RecordingSource) GetPost(id int) (*dto.PostInfo, error) {
+	info, err := s.source.GetPost(id)
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"posts", strconv.Itoa(id) + ".json"}
+	s.record(s.writeJSON(dto.PostDetail{Result: true, Post: *info}, elem...), elem...)
+	return info, nil
+}
+
+func (s *RecordingSource) GetBands() (*dto.BandsMain1, error) {
+	result, err := s.source.GetBands()
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"bands", "main.1.json"}
+	s.record(s.writeJSON(result, elem...), elem...)
+	return result, nil
+}
+
+func (s *RecordingSource) GetCharacters() (*dto.CharactersMain1, error) {
+	result, err := s.source.GetCharacters()
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"characters", "main.1.json"}
+	s.record(s.writeJSON(result, elem...), elem...)
+	return result, nil
+}
+
+func (s *RecordingSource) GetEvents() (*dto.EventsAll3, error) {
+	result, err := s.source.GetEvents()
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"events", "all.3.json"}
+	s.record(s.writeJSON(result, elem...), elem...)
+	return result, nil
+}
+
+func (s *RecordingSource) GetCards() (*dto.CardsAll5, error) {
+	result, err := s.source.GetCards()
+	if err != nil {
+		return nil, err
+	}
+	elem := []string{"cards", "all.5.json"}
+	s.record(s.writeJSON(result, elem...), elem...)
+	return result, nil
+}
+
+func (s *RecordingSource) GetAssetsFile(name, endpoint string) ([]byte, error) {
+	data, err := s.source.GetAssetsFile(name, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	elem := append([]string{"assets"}, strings.Split(name, "/")...)
+	s.record(s.writeFile(data, elem...), elem...)
+	return data, nil
+}
+
+// RecordSnapshot 按照空数据库初始化时的顺序完整抓取一次上游数据，并录制到 dir
+//
+// postLimit 限制录制的谱面帖子数量，小于等于 0 时不限制
+func RecordSnapshot(ctx context.Context, source Source, dir string, postLimit int) error {
+	rec := NewRecordingSource(source, dir)
+	// 中途取消或失败时同样写入已录制的帖子列表
+	defer rec.flushPostList()
+
+	all8, err := rec.GetAll8()
+	if err != nil {
+		return fmt.Errorf("failed to get songs all.8.json: %w", err)
+	}
+
+	idList := make([]int, 0, len(*all8))
+	for idStr := range *all8 {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		idList = append(idList, id)
+	}
+	slices.Sort(idList)
+
+	for _, id := range idList {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		log.Infof("recording song %d ...", id)
+		song, err := getSong(rec, id)
+		if err != nil {
+			log.Errorf("failed to record song %d: %v", id, err)
+			continue
+		}
+		for _, diff := range chartDiffsFromInfo(song.Info) {
+			if _, err := getChart(rec, song, dto.ChartDifficultyName(diff.label)); err != nil {
+				if _, ok := err.(*bestdori.NotExistError); !ok {
+					log.Errorf("failed to record chart %s for song %d: %v", diff.label, id, err)
+				}
+			}
+		}
+		for _, jacket := range song.GetJacket() {
+			if err := retry(func() error {
+				_, err := rec.GetJacket(jacket)
+				return err
+			}); err != nil {
+				log.Errorf("failed to record jacket %s for song %d: %v", jacket.JacketImage, id, err)
+			}
+		}
+		if err := retry(func() error {
+			_, err := rec.GetBGM(song)
+			return err
+		}); err != nil {
+			log.Errorf("failed to record BGM for song %d: %v", id, err)
+		}
+	}
+
+	for _, collector := range assetsCollectors {
+		tasks, err := collector.collect(rec)
+		if err != nil {
+			log.Errorf("failed to record %s list: %v", collector.category.Name, err)
+			continue
+		}
+		for _, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := retry(func() error {
+				_, err := rec.GetAssetsFile(task.name, task.endpoint)
+				return err
+			}); err != nil {
+				log.Errorf("failed to record assets %s: %v", task.name, err)
+			}
+		}
+	}
+
+	offset := 0
+	limit := 50
+	for postLimit <= 0 || offset < postLimit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		list, err := getPostList(rec, offset, limit)
+		if err != nil {
+			return fmt.Errorf("failed to get post list with offset %d: %w", offset, err)
+		}
+		for _, p := range list.Posts {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			log.Infof("recording post %d ...", p.Id)
+			if _, err := getPost(rec, p.Id); err != nil {
+				log.Errorf("failed to record post %d: %v", p.Id, err)
+			}
+		}
+		offset += limit
+		if len(list.Posts) < limit {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/pkg/log"
+)
+
+// defaultUpdateInterval 未配置更新间隔时的默认值（分钟）
+const defaultUpdateInterval = 10
+
+// Reconfigure 在不中断运行的情况下应用新的上游访问、定时更新与镜像配置
+//
+// 正在进行的更新会继续使用旧的数据源完成当前请求，之后的请求使用新的数据源
+func (du *DataUpdater) Reconfigure(conf *config.Config) {
+	source := NewSource(conf)
+	mirror := newPostMirror(conf)
+	setRetryAttempts(conf.API.Retry)
+
+	du.settingsMu.Lock()
+	du.source = source
+	du.postGapLimit = conf.API.Gap
+	du.updateInterval = conf.API.UpdateInterval
+	du.mirror = mirror
+	du.settingsMu.Unlock()
+
+	log.Infof("data updater reconfigured: retry %d, gap %d, update interval %d minutes", conf.API.Retry, conf.API.Gap, du.interval())
+}
+
+func (du *DataUpdater) currentSource() Source {
+	du.settingsMu.RLock()
+	defer du.settingsMu.RUnlock()
+	return du.source
+}
+
+func (du *DataUpdater) currentMirror() *postMirror {
+	du.settingsMu.RLock()
+	defer du.settingsMu.RUnlock()
+	return du.mirror
+}
+
+func (du *DataUpdater) gapLimit() int {
+	du.settingsMu.RLock()
+	defer du.settingsMu.RUnlock()
+	return du.postGapLimit
+}
+
+// interval 获取定时更新间隔（分钟）
+func (du *DataUpdater) interval() int {
+	du.settingsMu.RLock()
+	defer du.settingsMu.RUnlock()
+	if du.updateInterval <= 0 {
+		return defaultUpdateInterval
+	}
+	return du.updateInterval
+}
@@ -0,0 +1,149 @@
+package data
+
+import (
+	"strconv"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/bands"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/cards"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/characters"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/charts"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/endpoints"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/events"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/post"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/songs"
+	"github.com/WindowsSov8forUs/bestdori-api-go/uniapi"
+)
+
+// Source 上游数据源
+//
+// DataUpdater 只通过该接口获取歌曲、谱面、帖子与资源数据
+type Source interface {
+	// GetAll8 获取 songs/all.8.json
+	GetAll8() (*dto.SongsAll8, error)
+	// GetSong 获取歌曲详细信息
+	GetSong(id int) (*dto.SongInfo, error)
+	// GetChart 获取官方谱面，谱面不存在时返回 *bestdori.NotExistError
+	GetChart(id int, diff dto.ChartDifficultyName) (*dto.Chart, error)
+	// GetJacket 获取歌曲封面图片
+	GetJacket(jacket songs.Jacket) ([]byte, error)
+	// GetBGM 获取歌曲音频
+	GetBGM(song *songs.Song) ([]byte, error)
+	// GetPostList 按时间升序获取社区谱面帖子列表
+	GetPostList(offset, limit int) (*dto.PostList, error)
+	// GetPost 获取帖子详细信息
+	GetPost(id int) (*dto.PostInfo, error)
+	// GetBands 获取主要乐队信息
+	GetBands() (*dto.BandsMain1, error)
+	// GetCharacters 获取主要角色信息
+	GetCharacters() (*dto.CharactersMain1, error)
+	// GetEvents 获取活动信息
+	GetEvents() (*dto.EventsAll3, error)
+	// GetCards 获取卡牌信息
+	GetCards() (*dto.CardsAll5, error)
+	// GetAssetsFile 获取资源文件，name 为资源存储名称，endpoint 为 Bestdori 上的资源路径
+	GetAssetsFile(name, endpoint string) ([]byte, error)
+}
+
+// BestdoriSource 通过 Bestdori 在线接口获取数据
+type BestdoriSource struct {
+	bestdoriAPI *uniapi.UniAPI
+	niconiAPI   *uniapi.UniAPI
+}
+
+func NewBestdoriSource(bestdoriAPI, niconiAPI *uniapi.UniAPI) *BestdoriSource {
+	return &BestdoriSource{
+		bestdoriAPI: bestdoriAPI,
+		niconiAPI:   niconiAPI,
+	}
+}
+
+func (s *BestdoriSource) GetAll8() (*dto.SongsAll8, error) {
+	return songs.GetAll8(s.bestdoriAPI)
+}
+
+func (s *BestdoriSource) GetSong(id int) (*dto.SongInfo, error) {
+	song, err := songs.GetSong(s.bestdoriAPI, id)
+	if err != nil {
+		return nil, err
+	}
+	return song.Info, nil
+}
+
+func (s *BestdoriSource) GetChart(id int, diff dto.ChartDifficultyName) (*dto.Chart, error) {
+	chart, err := charts.GetChart(s.bestdoriAPI, id, diff)
+	if err != nil {
+		if e, ok := err.(*uniapi.ResponseStatusError); ok && e.StatusCode() == 404 {
+			return nil, &bestdori.NotExistError{
+				Target: string(diff) + " chart of song " + strconv.Itoa(id),
+			}
+		}
+		return nil, err
+	}
+	return chart, nil
+}
+
+func (s *BestdoriSource) GetJacket(jacket songs.Jacket) ([]byte, error) {
+	data, err := uniapi.Get[[]byte](s.bestdoriAPI, jacket.Endpoint(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return *data, nil
+}
+
+func (s *BestdoriSource) GetBGM(song *songs.Song) ([]byte, error) {
+	endpoint := endpoints.SongsSound(string(song.DefaultServer()), song.Id)
+	data, err := uniapi.Get[[]byte](s.bestdoriAPI, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *data, nil
+}
+
+func (s *BestdoriSource) GetPostList(offset, limit int) (*dto.PostList, error) {
+	return post.GetList(
+		s.bestdoriAPI,
+		"",
+		false,
+		"SELF_POST",
+		"chart",
+		nil,
+		"",
+		post.OrderTimeAsc,
+		limit,
+		offset,
+	)
+}
+
+func (s *BestdoriSource) GetPost(id int) (*dto.PostInfo, error) {
+	p, err := post.GetPost(s.bestdoriAPI, s.niconiAPI, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.Info, nil
+}
+
+func (s *BestdoriSource) GetBands() (*dto.BandsMain1, error) {
+	return bands.GetMain(s.bestdoriAPI)
+}
+
+func (s *BestdoriSource) GetCharacters() (*dto.CharactersMain1, error) {
+	return characters.GetMain1(s.bestdoriAPI)
+}
+
+func (s *BestdoriSource) GetEvents() (*dto.EventsAll3, error) {
+	return events.GetAll3(s.bestdoriAPI)
+}
+
+func (s *BestdoriSource) GetCards() (*dto.CardsAll5, error) {
+	return cards.GetAll5(s.bestdoriAPI)
+}
+
+func (s *BestdoriSource) GetAssetsFile(_, endpoint string) ([]byte, error) {
+	data, err := uniapi.Get[[]byte](s.bestdoriAPI, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *data, nil
+}
@@ -0,0 +1,51 @@
+package data
+
+import "time"
+
+// fatalFailureThreshold 连续更新失败达到该次数时视为更新器处于故障状态
+const fatalFailureThreshold = 3
+
+// UpdaterStatus 数据更新器状态
+type UpdaterStatus struct {
+	Running             bool      `json:"running"`
+	Synced              bool      `json:"synced"`
+	LastSuccess         time.Time `json:"lastSuccess,omitzero"`
+	LastFailure         time.Time `json:"lastFailure,omitzero"`
+	LastError           string    `json:"lastError,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Fatal               bool      `json:"fatal"`
+}
+
+// recordUpdateResult 记录一次初始化或更新的结果，任务被取消时不记录
+func (du *DataUpdater) recordUpdateResult(err error) {
+	if err != nil && du.ctx.Err() != nil {
+		return
+	}
+
+	du.mu.Lock()
+	defer du.mu.Unlock()
+
+	if err != nil {
+		syncFailures.WithLabelValues("update").Inc()
+		du.status.LastFailure = time.Now()
+		du.status.LastError = err.Error()
+		du.status.ConsecutiveFailures++
+		return
+	}
+	du.status.LastSuccess = time.Now()
+	du.status.LastError = ""
+	du.status.ConsecutiveFailures = 0
+	syncLastSuccess.Set(float64(du.status.LastSuccess.Unix()))
+}
+
+// Status 获取数据更新器状态
+func (du *DataUpdater) Status() UpdaterStatus {
+	du.mu.Lock()
+	defer du.mu.Unlock()
+
+	status := du.status
+	status.Running = du.updateRunning
+	status.Synced = !status.LastSuccess.IsZero()
+	status.Fatal = status.ConsecutiveFailures >= fatalFailureThreshold
+	return status
+}
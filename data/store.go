@@ -0,0 +1,24 @@
+package data
+
+import (
+	"context"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+
+	"anon-bestdori-database/database"
+)
+
+// Store DataUpdater 使用的数据库操作
+//
+// 默认实现为 *database.Database
+type Store interface {
+	GetSongByID(ctx context.Context, id int) (*dto.SongInfo, error)
+	UpsertSong(ctx context.Context, id int, song *dto.SongInfo) error
+	GetPostByID(ctx context.Context, id int) (*dto.PostInfo, error)
+	UpsertPost(ctx context.Context, id int, post *dto.PostInfo) error
+	GetNewestPostID(ctx context.Context) (int, error)
+	GetChartByID(ctx context.Context, id string) (*dto.Chart, error)
+	UpsertChart(ctx context.Context, id string, chart *dto.Chart) error
+	GetPostMirror(ctx context.Context, id int) (*database.PostMirror, error)
+	UpsertPostMirror(ctx context.Context, mirror *database.PostMirror) error
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -40,10 +41,17 @@ func (du *DataUpdater) Update() error {
 	}
 	if err := du.updateSongs(); err != nil {
 		log.Errorf("failed to update songs data: %v", err)
+		syncFailures.WithLabelValues("songs").Inc()
 		return err
 	}
 	if err := du.updatePosts(); err != nil {
 		log.Errorf("failed to update posts data: %v", err)
+		syncFailures.WithLabelValues("posts").Inc()
+		return err
+	}
+	if err := du.updateAssets(false); err != nil {
+		log.Errorf("failed to update assets: %v", err)
+		syncFailures.WithLabelValues("assets").Inc()
 		return err
 	}
 	return nil
@@ -53,7 +61,7 @@ func (du *DataUpdater) updateSongs() error {
 	if err := du.ctx.Err(); err != nil {
 		return err
 	}
-	all8, err := songs.GetAll8(du.bestdoriAPI)
+	all8, err := du.currentSource().GetAll8()
 	if err != nil {
 		log.Errorf("failed to get songs all.8.json: %v", err)
 		return err
@@ -74,12 +82,13 @@ func (du *DataUpdater) updateSongs() error {
 			return err
 		}
 		info := (*all8)[strconv.Itoa(id)]
-		existing, _ := du.db.GetSongByID(du.ctx, id)
+		existing, _ := du.db.GetSongByID(du.dbCtx(), id)
 		if !needsSongUpdate(existing, info) {
 			continue
 		}
 		if _, err := du.UpdateSongByID(id); err != nil {
 			log.Errorf("failed to update song %d: %v", id, err)
+			syncFailures.WithLabelValues("song").Inc()
 		}
 	}
 	return nil
@@ -91,16 +100,17 @@ func (du *DataUpdater) UpdateSongByID(id int) (bool, error) {
 	}
 	log.Infof("updating song %d info...", id)
 
-	song, err := getSong(du.bestdoriAPI, id)
+	song, err := getSong(du.currentSource(), id)
 	if err != nil {
 		return false, err
 	}
 
-	if err := du.db.UpsertSong(du.ctx, id, song.Info); err != nil {
+	if err := du.db.UpsertSong(du.dbCtx(), id, song.Info); err != nil {
 		log.Errorf("failed to upsert song %d: %v", id, err)
 		return true, err
 	}
 	log.Infof("updated song %d", id)
+	syncSongsUpdated.Inc()
 
 	du.ensureSongAssets(song)
 	du.ensureSongCharts(song)
@@ -133,7 +143,7 @@ func (du *DataUpdater) updatePosts() error {
 			}
 			currentID++
 			if du.shouldStopPostUpdates(info.LastID, currentID) {
-				log.Infof("post update stopped, consecutive missing posts exceed %d", du.postGapLimit)
+				log.Infof("post update stopped, consecutive missing posts exceed %d", du.gapLimit())
 				break
 			}
 			continue
@@ -153,16 +163,18 @@ func (du *DataUpdater) UpdatePostByID(id int) (bool, error) {
 	if err := du.ctx.Err(); err != nil {
 		return false, err
 	}
-	postInst, err := getPost(du.bestdoriAPI, du.niconiAPI, id)
+	postInfo, err := getPost(du.currentSource(), id)
 	if err != nil {
 		return false, err
 	}
-	if postInst.Info.CategoryName == "SELF_POST" && postInst.Info.CategoryId == "chart" {
+	if postInfo.CategoryName == "SELF_POST" && postInfo.CategoryId == "chart" {
 		log.Infof("updating post %d...", id)
-		if err := du.db.UpsertPost(du.ctx, id, postInst.Info); err != nil {
+		du.mirrorPostAssets(id, postInfo)
+		if err := du.db.UpsertPost(du.dbCtx(), id, postInfo); err != nil {
 			return true, err
 		}
 		log.Infof("updated post %d", id)
+		syncPostsUpdated.Inc()
 	}
 	return true, nil
 }
@@ -189,11 +201,13 @@ func (du *DataUpdater) ensureSongAssets(song *songs.Song) {
 
 func (du *DataUpdater) ensureSongJackets(song *songs.Song) {
 	for _, jacket := range song.GetJacket() {
-		jacketName := fmt.Sprintf("musicjacket/%s.png", jacket.JacketImage)
-		if _, err := files.GetAssets(jacketName); err != nil {
+		jacketName := files.CategoryMusicJacket.Path(jacket.JacketImage + ".png")
+		if exists, err := files.AssetsExists(jacketName); err != nil {
+			log.Errorf("failed to check jacket %s for song %d: %v", jacket.JacketImage, song.Id, err)
+		} else if !exists {
 			log.Infof("downloading missing jacket %s for song %d", jacket.JacketImage, song.Id)
 			if err := retry(func() error {
-				return downloadMusicJacket(jacket)
+				return downloadMusicJacket(du.currentSource(), jacket)
 			}); err != nil {
 				log.Errorf("failed to update jacket %s for song %d: %v", jacket.JacketImage, song.Id, err)
 			} else {
@@ -204,11 +218,13 @@ func (du *DataUpdater) ensureSongJackets(song *songs.Song) {
 }
 
 func (du *DataUpdater) ensureSongBGM(song *songs.Song) {
-	bgmName := fmt.Sprintf("sound/bgm%03d.mp3", song.Id)
-	if _, err := files.GetAssets(bgmName); err != nil {
+	bgmName := files.CategorySound.Path(fmt.Sprintf("bgm%03d.mp3", song.Id))
+	if exists, err := files.AssetsExists(bgmName); err != nil {
+		log.Errorf("failed to check BGM for song %d: %v", song.Id, err)
+	} else if !exists {
 		log.Infof("downloading missing BGM for song %d", song.Id)
 		if err := retry(func() error {
-			return downloadBGM(song)
+			return downloadBGM(du.currentSource(), song)
 		}); err != nil {
 			log.Errorf("failed to update BGM for song %d: %v", song.Id, err)
 		} else {
@@ -220,18 +236,18 @@ func (du *DataUpdater) ensureSongBGM(song *songs.Song) {
 func (du *DataUpdater) ensureSongCharts(song *songs.Song) {
 	for _, diff := range chartDiffsFromInfo(song.Info) {
 		chartID := fmt.Sprintf("%d-%s", song.Id, diff.label)
-		if existingChart, _ := du.db.GetChartByID(du.ctx, chartID); existingChart != nil {
+		if existingChart, _ := du.db.GetChartByID(du.dbCtx(), chartID); existingChart != nil {
 			continue
 		}
 		log.Infof("updating missing chart %s for song %d", chartID, song.Id)
-		chart, err := getChart(song, dto.ChartDifficultyName(diff.label))
+		chart, err := getChart(du.currentSource(), song, dto.ChartDifficultyName(diff.label))
 		if err != nil {
 			if _, ok := err.(*bestdori.NotExistError); !ok {
 				log.Errorf("failed to get chart %s for song %d: %v", diff.label, song.Id, err)
 			}
 			continue
 		}
-		if err := du.db.UpsertChart(du.ctx, chartID, chart); err != nil {
+		if err := du.db.UpsertChart(du.dbCtx(), chartID, chart); err != nil {
 			log.Errorf("failed to upsert chart %s: %v", chartID, err)
 		} else {
 			log.Infof("updated chart %s", chartID)
@@ -265,7 +281,7 @@ func (du *DataUpdater) loadPostUpdateInfo() (PostUpdateInfo, error) {
 	}
 	var info PostUpdateInfo
 	if len(data) == 0 {
-		newestId, err := du.db.GetNewestPostID(du.ctx)
+		newestId, err := du.db.GetNewestPostID(du.dbCtx())
 		if err == nil && newestId > 0 {
 			info.LastID = newestId
 		}
@@ -290,10 +306,11 @@ func (du *DataUpdater) persistPostUpdateInfo(info PostUpdateInfo) {
 }
 
 func (du *DataUpdater) shouldStopPostUpdates(lastID, currentID int) bool {
-	if du.postGapLimit <= 0 {
+	gap := du.gapLimit()
+	if gap <= 0 {
 		return false
 	}
-	return currentID-lastID > du.postGapLimit
+	return currentID-lastID > gap
 }
 
 func difficultiesChanged(existing map[string]dto.SongDifficulty, latest map[string]dto.SongsAll5Difficulty) bool {
@@ -336,7 +353,7 @@ func normalizeSongsAll8Info(info dto.SongsAll8Info) dto.SongsAll8Info {
 	return info
 }
 
-// StartUpdating schedules periodic updates every 10 minutes and ensures only one run at a time.
+// StartUpdating schedules periodic updates every api.update_interval minutes and ensures only one run at a time.
 func (du *DataUpdater) StartUpdating() {
 	go func() {
 		ticker := time.NewTicker(time.Minute)
@@ -348,8 +365,8 @@ func (du *DataUpdater) StartUpdating() {
 				du.waitForRunningUpdate()
 				return
 			case t := <-ticker.C:
-				now := t
-				if now.Minute()%10 == 0 {
+				// 按自 Unix 纪元起的分钟数对齐，间隔为 10 时与整十分钟一致
+				if t.Unix()/60%int64(du.interval()) == 0 {
 					du.launchScheduledUpdate()
 				}
 			}
@@ -382,7 +399,9 @@ func (du *DataUpdater) runScheduledUpdate(done chan struct{}) {
 	}()
 
 	log.Info("updating job running...")
-	if err := du.Update(); err != nil {
+	err := du.Update()
+	du.recordUpdateResult(err)
+	if err != nil {
 		if du.ctx.Err() != nil && err == du.ctx.Err() {
 			log.Infof("update canceled: %v", err)
 		} else {
@@ -393,6 +412,31 @@ func (du *DataUpdater) runScheduledUpdate(done chan struct{}) {
 	}
 }
 
+// Stop 通知数据更新器在安全点停止，并等待正在进行的更新结束
+//
+// 正在执行的数据库写入会完成，ctx 到期时不再等待
+func (du *DataUpdater) Stop(ctx context.Context) error {
+	du.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		du.waitForRunningUpdate()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dbCtx 获取数据库操作使用的上下文，停止更新时不会中断已开始的写入
+func (du *DataUpdater) dbCtx() context.Context {
+	return context.WithoutCancel(du.ctx)
+}
+
 func (du *DataUpdater) waitForRunningUpdate() {
 	du.mu.Lock()
 	done := du.updateDone
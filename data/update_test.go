@@ -0,0 +1,307 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/database"
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	// 资源与缓存目录通过配置指向临时目录，不在源码目录中留下文件
+	dir, err := os.MkdirTemp("", "anon-data-test-")
+	if err != nil {
+		panic(err)
+	}
+	conf := &config.Config{
+		Log:    config.LogConfig{Level: "error", Stdout: true},
+		Assets: config.AssetsConfig{Backend: "filesystem", Path: filepath.Join(dir, "assets")},
+		Cache:  config.CacheConfig{Path: filepath.Join(dir, "cache")},
+	}
+	if err := log.Init(conf, "test"); err != nil {
+		panic(err)
+	}
+	if err := files.Init(conf); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// memoryStore 基于内存的 Store 实现
+type memoryStore struct {
+	mu      sync.Mutex
+	songs   map[int]*dto.SongInfo
+	posts   map[int]*dto.PostInfo
+	charts  map[string]*dto.Chart
+	mirrors map[int]*database.PostMirror
+
+	songUpserts []int
+	postUpserts []int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		songs:   map[int]*dto.SongInfo{},
+		posts:   map[int]*dto.PostInfo{},
+		charts:  map[string]*dto.Chart{},
+		mirrors: map[int]*database.PostMirror{},
+	}
+}
+
+func (s *memoryStore) GetSongByID(_ context.Context, id int) (*dto.SongInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.songs[id], nil
+}
+
+func (s *memoryStore) UpsertSong(_ context.Context, id int, song *dto.SongInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.songs[id] = song
+	s.songUpserts = append(s.songUpserts, id)
+	return nil
+}
+
+func (s *memoryStore) GetPostByID(_ context.Context, id int) (*dto.PostInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.posts[id], nil
+}
+
+func (s *memoryStore) UpsertPost(_ context.Context, id int, post *dto.PostInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.posts[id] = post
+	s.postUpserts = append(s.postUpserts, id)
+	return nil
+}
+
+func (s *memoryStore) GetNewestPostID(context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	newest := 0
+	for id := range s.posts {
+		newest = max(newest, id)
+	}
+	return newest, nil
+}
+
+func (s *memoryStore) GetChartByID(_ context.Context, id string) (*dto.Chart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.charts[id], nil
+}
+
+func (s *memoryStore) UpsertChart(_ context.Context, id string, chart *dto.Chart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.charts[id] = chart
+	return nil
+}
+
+func (s *memoryStore) GetPostMirror(_ context.Context, id int) (*database.PostMirror, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mirrors[id], nil
+}
+
+func (s *memoryStore) UpsertPostMirror(_ context.Context, mirror *database.PostMirror) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mirrors[mirror.PostID] = mirror
+	return nil
+}
+
+// writeFixture 在快照目录中写入文件，v 为 []byte 时原样写入，否则编码为 JSON
+func writeFixture(t *testing.T, dir string, v any, elem ...string) {
+	t.Helper()
+	data, ok := v.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(append([]string{dir}, elem...)...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestUpdater(t *testing.T, store Store, source Source, gap int) *DataUpdater {
+	t.Helper()
+	conf := &config.Config{API: config.APIConfig{Retry: 1, Gap: gap}}
+	du := NewDataUpdaterWithSource(store, conf, context.Background(), source)
+	t.Cleanup(func() { _ = du.Stop(context.Background()) })
+	return du
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func testSongInfo(title string, level int) dto.SongInfo {
+	published := []*string{strPtr("1600000000000"), nil, nil, nil, nil}
+	info := dto.SongInfo{}
+	info.MusicTitle = []*string{strPtr(title), nil, nil, nil, nil}
+	info.Tag = "normal"
+	info.BandId = 1
+	info.JacketImage = []string{"jacket_" + title}
+	info.PublishedAt = published
+	info.ClosedAt = []*string{nil, nil, nil, nil, nil}
+	info.Length = 120
+	info.Difficulty = map[string]dto.SongDifficulty{
+		"3": {PlayLevel: level},
+	}
+	return info
+}
+
+func testAll8Info(info dto.SongInfo) dto.SongsAll8Info {
+	all8 := info.SongsAll8Info
+	all8.Difficulty = map[string]dto.SongsAll5Difficulty{}
+	for key, diff := range info.Difficulty {
+		all8.Difficulty[key] = dto.SongsAll5Difficulty{PlayLevel: diff.PlayLevel, PublishedAt: diff.PublishedAt}
+	}
+	return all8
+}
+
+func TestNeedsSongUpdate(t *testing.T) {
+	existing := testSongInfo("song", 25)
+	latest := testAll8Info(existing)
+
+	renamed := latest
+	renamed.MusicTitle = []*string{strPtr("renamed"), nil, nil, nil, nil}
+
+	relevelled := testAll8Info(testSongInfo("song", 26))
+
+	withSpecial := testAll8Info(existing)
+	withSpecial.Difficulty["4"] = dto.SongsAll5Difficulty{PlayLevel: 28}
+
+	published := testAll8Info(existing)
+	published.Difficulty["3"] = dto.SongsAll5Difficulty{
+		PlayLevel:   25,
+		PublishedAt: &[]*string{strPtr("1700000000000"), nil, nil, nil, nil},
+	}
+
+	tests := []struct {
+		name     string
+		existing *dto.SongInfo
+		latest   dto.SongsAll8Info
+		want     bool
+	}{
+		{"missing", nil, latest, true},
+		{"unchanged", &existing, latest, false},
+		{"title changed", &existing, renamed, true},
+		{"level changed", &existing, relevelled, true},
+		{"difficulty added", &existing, withSpecial, true},
+		{"difficulty published", &existing, published, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsSongUpdate(tt.existing, tt.latest); got != tt.want {
+				t.Errorf("needsSongUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateSongs(t *testing.T) {
+	dir := t.TempDir()
+	unchanged := testSongInfo("unchanged", 25)
+	changed := testSongInfo("changed", 27)
+	added := testSongInfo("added", 24)
+
+	writeFixture(t, dir, dto.SongsAll8{
+		"1": testAll8Info(unchanged),
+		"2": testAll8Info(changed),
+		"3": testAll8Info(added),
+	}, "songs", "all.8.json")
+	writeFixture(t, dir, unchanged, "songs", "1.json")
+	writeFixture(t, dir, changed, "songs", "2.json")
+	writeFixture(t, dir, added, "songs", "3.json")
+	writeFixture(t, dir, dto.Chart{}, "charts", "3", "expert.json")
+	writeFixture(t, dir, []byte("jacket"), "assets", "musicjacket", "jacket_added.png")
+	writeFixture(t, dir, []byte("bgm"), "assets", "sound", "bgm003.mp3")
+
+	store := newMemoryStore()
+	store.songs[1] = &unchanged
+	stale := testSongInfo("changed", 26)
+	store.songs[2] = &stale
+
+	du := newTestUpdater(t, store, NewFixtureSource(dir), 0)
+	if err := du.updateSongs(); err != nil {
+		t.Fatalf("updateSongs() error = %v", err)
+	}
+
+	if got, want := store.songUpserts, []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("upserted songs = %v, want %v", got, want)
+	}
+	if got := store.songs[2].Difficulty["3"].PlayLevel; got != 27 {
+		t.Errorf("song 2 level = %d, want 27", got)
+	}
+	if _, ok := store.charts["3-expert"]; !ok {
+		t.Error("chart 3-expert was not stored")
+	}
+	for _, name := range []string{"musicjacket/jacket_added.png", "sound/bgm003.mp3"} {
+		if exists, err := files.AssetsExists(name); err != nil || !exists {
+			t.Errorf("assets %s exists = %v, %v, want true", name, exists, err)
+		}
+	}
+}
+
+func testPostDetail(categoryID string) dto.PostDetail {
+	return dto.PostDetail{
+		Result: true,
+		Post: dto.PostInfo{
+			CategoryName: "SELF_POST",
+			CategoryId:   categoryID,
+			Title:        strPtr("post"),
+			Chart:        &[]map[string]any{},
+		},
+	}
+}
+
+func TestUpdatePostsGap(t *testing.T) {
+	dir := t.TempDir()
+	// 12、13 缺失但未超过间隔，15 不是谱面帖子，16 之后全部缺失
+	writeFixture(t, dir, testPostDetail("chart"), "posts", "11.json")
+	writeFixture(t, dir, testPostDetail("chart"), "posts", "14.json")
+	writeFixture(t, dir, testPostDetail("text"), "posts", "15.json")
+	writeFixture(t, dir, testPostDetail("chart"), "posts", "19.json")
+
+	store := newMemoryStore()
+	store.posts[10] = &dto.PostInfo{}
+
+	du := newTestUpdater(t, store, NewFixtureSource(dir), 3)
+	if err := du.updatePosts(); err != nil {
+		t.Fatalf("updatePosts() error = %v", err)
+	}
+
+	if got, want := store.postUpserts, []int{11, 14}; !slices.Equal(got, want) {
+		t.Errorf("upserted posts = %v, want %v", got, want)
+	}
+
+	info, err := du.loadPostUpdateInfo()
+	if err != nil {
+		t.Fatalf("loadPostUpdateInfo() error = %v", err)
+	}
+	if info.LastID != 15 {
+		t.Errorf("last post id = %d, want 15", info.LastID)
+	}
+}
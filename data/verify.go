@@ -0,0 +1,120 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/endpoints"
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/songs"
+
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+)
+
+// AssetsVerifyResult 资源校验与修复结果
+type AssetsVerifyResult struct {
+	*files.VerifyReport
+	Repaired []string // 重新下载成功的资源
+	Failed   []string // 重新下载失败的资源
+	Orphans  []string // 不属于任何已知歌曲或类别的资源
+}
+
+// songAssetsTasks 获取歌曲的封面与音频资源
+func songAssetsTasks(song *songs.Song) []assetsTask {
+	var tasks []assetsTask
+	for _, jacket := range song.GetJacket() {
+		tasks = append(tasks, assetsTask{
+			name:     files.CategoryMusicJacket.Path(jacket.JacketImage + ".png"),
+			endpoint: jacket.Endpoint(),
+		})
+	}
+	if server := song.DefaultServer(); server != "" {
+		tasks = append(tasks, assetsTask{
+			name:     files.CategorySound.Path(fmt.Sprintf("bgm%03d.mp3", song.Id)),
+			endpoint: endpoints.SongsSound(string(server), song.Id),
+		})
+	}
+	return tasks
+}
+
+// SongAssetsNames 获取歌曲的封面与音频资源名称
+func SongAssetsNames(song *songs.Song) []string {
+	tasks := songAssetsTasks(song)
+	names := make([]string, len(tasks))
+	for i, task := range tasks {
+		names[i] = task.name
+	}
+	return names
+}
+
+// expectedAssets 根据数据库中的歌曲与上游列表数据获取应当存在的全部资源
+func (du *DataUpdater) expectedAssets() ([]assetsTask, error) {
+	all8, err := du.currentSource().GetAll8()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get songs all.8.json: %w", err)
+	}
+
+	var tasks []assetsTask
+	for _, id := range sortedIDs(*all8) {
+		info, err := du.db.GetSongByID(du.dbCtx(), id)
+		if err != nil {
+			log.Errorf("failed to get song %d from database: %v", id, err)
+			continue
+		}
+		if info == nil {
+			continue
+		}
+		tasks = append(tasks, songAssetsTasks(&songs.Song{Id: id, Info: info})...)
+	}
+
+	for _, collector := range assetsCollectors {
+		collected, err := collector.collect(du.currentSource())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s list: %w", collector.category.Name, err)
+		}
+		tasks = append(tasks, collected...)
+	}
+	return tasks, nil
+}
+
+// VerifyAssets 校验全部资源，重新下载损坏或缺失的资源并报告孤立资源
+func (du *DataUpdater) VerifyAssets() (*AssetsVerifyResult, error) {
+	report, err := files.VerifyAssets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify assets: %w", err)
+	}
+	result := &AssetsVerifyResult{VerifyReport: report}
+
+	tasks, err := du.expectedAssets()
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		if err := du.ctx.Err(); err != nil {
+			return nil, err
+		}
+		expected[task.name] = true
+		downloaded, err := du.ensureAssets(task)
+		switch {
+		case err != nil:
+			result.Failed = append(result.Failed, task.name)
+		case downloaded:
+			result.Repaired = append(result.Repaired, task.name)
+		}
+	}
+
+	// 帖子镜像资源随帖子更新生成，不参与孤立资源检查
+	postsPrefix := files.CategoryPosts.Name + "/"
+	for _, name := range report.Objects {
+		if !expected[name] && !files.IsVariantName(name) && !strings.HasPrefix(name, postsPrefix) {
+			result.Orphans = append(result.Orphans, name)
+		}
+	}
+
+	if err := files.FlushManifest(); err != nil {
+		return nil, fmt.Errorf("failed to save assets manifest: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// APIKey API 访问密钥，数据库中只保存密钥的 SHA-256 摘要
+type APIKey struct {
+	Hash      string    `bson:"_id" json:"-"`
+	Name      string    `bson:"name" json:"name"`
+	RateLimit int       `bson:"rateLimit,omitempty" json:"rateLimit,omitempty"` // 每个限流窗口允许的请求数，0 表示使用路由组的默认值
+	Disabled  bool      `bson:"disabled,omitempty" json:"disabled,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// HashAPIKey 计算密钥摘要
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type APIKeys struct {
+	coll *qmgo.Collection
+}
+
+func NewAPIKeys(coll *qmgo.Collection) *APIKeys {
+	return &APIKeys{coll: coll}
+}
+
+func (k *APIKeys) Insert(ctx context.Context, apiKey *APIKey) error {
+	_, err := k.coll.InsertOne(ctx, apiKey)
+	return err
+}
+
+func (k *APIKeys) GetByKey(ctx context.Context, key string) (*APIKey, error) {
+	var apiKey APIKey
+	err := k.coll.Find(ctx, bson.M{"_id": HashAPIKey(key)}).One(&apiKey)
+	if err != nil {
+		if qmgo.IsErrNoDocuments(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// Database proxy methods for api keys
+func (d *Database) InsertAPIKey(ctx context.Context, apiKey *APIKey) error {
+	return d.apiKeys.Insert(ctx, apiKey)
+}
+
+func (d *Database) GetAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	return d.apiKeys.GetByKey(ctx, key)
+}
@@ -39,6 +39,22 @@ func (c *Charts) GetByID(ctx context.Context, id string) (*dto.Chart, error) {
 	return &res.Chart, nil
 }
 
+func (c *Charts) GetByIDs(ctx context.Context, ids []string) (map[string]*dto.Chart, error) {
+	var results []struct {
+		ID    string    `bson:"_id"`
+		Chart dto.Chart `bson:"chart"`
+	}
+	err := c.coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}).All(&results)
+	if err != nil {
+		return nil, err
+	}
+	charts := make(map[string]*dto.Chart, len(results))
+	for i := range results {
+		charts[results[i].ID] = &results[i].Chart
+	}
+	return charts, nil
+}
+
 // Database proxy methods for charts
 func (d *Database) UpsertChart(ctx context.Context, id string, chart *dto.Chart) error {
 	return d.charts.Upsert(ctx, id, chart)
@@ -47,3 +63,7 @@ func (d *Database) UpsertChart(ctx context.Context, id string, chart *dto.Chart)
 func (d *Database) GetChartByID(ctx context.Context, id string) (*dto.Chart, error) {
 	return d.charts.GetByID(ctx, id)
 }
+
+func (d *Database) GetChartsByIDs(ctx context.Context, ids []string) (map[string]*dto.Chart, error) {
+	return d.charts.GetByIDs(ctx, ids)
+}
@@ -4,19 +4,25 @@ import (
 	"context"
 
 	"github.com/qiniu/qmgo"
+	"github.com/qiniu/qmgo/options"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
 
 	"anon-bestdori-database/config"
 )
 
 type Database struct {
-	cli    *qmgo.Client
-	posts  *Posts
-	songs  *Songs
-	charts *Charts
+	cli         *qmgo.Client
+	posts       *Posts
+	songs       *Songs
+	charts      *Charts
+	apiKeys     *APIKeys
+	postMirrors *PostMirrors
 }
 
 func NewClient(ctx context.Context, conf *config.Config) (*Database, error) {
-	cli, err := qmgo.NewClient(ctx, &qmgo.Config{Uri: conf.Mongo.URI})
+	cli, err := qmgo.NewClient(ctx, &qmgo.Config{Uri: conf.Mongo.URI}, options.ClientOptions{
+		ClientOptions: mongooptions.Client().SetMonitor(newCommandMonitor()),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -28,13 +34,20 @@ func NewClient(ctx context.Context, conf *config.Config) (*Database, error) {
 	db := cli.Database("anon_db")
 
 	return &Database{
-		cli:    cli,
-		posts:  NewPosts(db.Collection("posts")),
-		songs:  NewSongs(db.Collection("songs")),
-		charts: NewCharts(db.Collection("charts")),
+		cli:         cli,
+		posts:       NewPosts(db.Collection("posts")),
+		songs:       NewSongs(db.Collection("songs")),
+		charts:      NewCharts(db.Collection("charts")),
+		apiKeys:     NewAPIKeys(db.Collection("api_keys")),
+		postMirrors: NewPostMirrors(db.Collection("post_mirrors")),
 	}, nil
 }
 
 func (d *Database) Close(ctx context.Context) error {
 	return d.cli.Close(ctx)
 }
+
+// Ping 检查与数据库的连接，timeout 为超时秒数
+func (d *Database) Ping(timeout int64) error {
+	return d.cli.Ping(timeout)
+}
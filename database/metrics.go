@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+
+	"anon-bestdori-database/pkg/log"
+)
+
+var (
+	mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "anon",
+		Subsystem: "mongo",
+		Name:      "command_duration_seconds",
+		Help:      "MongoDB command latency by command and collection.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "collection"})
+	mongoCommandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "mongo",
+		Name:      "command_errors_total",
+		Help:      "Failed MongoDB commands by command and collection.",
+	}, []string{"command", "collection"})
+)
+
+// newCommandMonitor 创建记录命令耗时并记录失败命令的 MongoDB 命令监视器
+func newCommandMonitor() *event.CommandMonitor {
+	// 命令开始时记录集合名称，结束事件中不再包含命令内容
+	var collections sync.Map
+
+	finish := func(requestID int64) string {
+		if v, ok := collections.LoadAndDelete(requestID); ok {
+			return v.(string)
+		}
+		return ""
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			collection, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+			collections.Store(e.RequestID, collection)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			collection := finish(e.RequestID)
+			mongoCommandDuration.WithLabelValues(e.CommandName, collection).Observe(e.Duration.Seconds())
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			collection := finish(e.RequestID)
+			mongoCommandDuration.WithLabelValues(e.CommandName, collection).Observe(e.Duration.Seconds())
+			mongoCommandErrors.WithLabelValues(e.CommandName, collection).Inc()
+			// 上下文中带有请求 ID 时一并记录，便于关联请求
+			log.FromContext(ctx).Errorf("mongo command %s on %s failed: %v", e.CommandName, collection, e.Failure)
+		},
+	}
+}
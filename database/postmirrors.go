@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MirroredAssets 单个镜像资源
+type MirroredAssets struct {
+	Source string `bson:"source"` // 镜像时的上游链接
+	Name   string `bson:"name"`   // 资源名称
+}
+
+// PostMirror 帖子自定义歌曲资源的镜像记录
+//
+// 帖子中始终保存上游链接，响应时按此记录替换为镜像链接
+type PostMirror struct {
+	PostID int                       `bson:"_id"`
+	Assets map[string]MirroredAssets `bson:"assets"` // 按资源类型（audio、cover）
+}
+
+type PostMirrors struct {
+	coll *qmgo.Collection
+}
+
+func NewPostMirrors(coll *qmgo.Collection) *PostMirrors {
+	return &PostMirrors{coll: coll}
+}
+
+func (m *PostMirrors) Upsert(ctx context.Context, mirror *PostMirror) error {
+	_, err := m.coll.Upsert(ctx, bson.M{"_id": mirror.PostID}, mirror)
+	return err
+}
+
+func (m *PostMirrors) GetByID(ctx context.Context, id int) (*PostMirror, error) {
+	var mirror PostMirror
+	err := m.coll.Find(ctx, bson.M{"_id": id}).One(&mirror)
+	if err != nil {
+		if qmgo.IsErrNoDocuments(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mirror, nil
+}
+
+func (m *PostMirrors) GetByIDs(ctx context.Context, ids []int) (map[int]*PostMirror, error) {
+	var mirrors []PostMirror
+	err := m.coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}).All(&mirrors)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int]*PostMirror, len(mirrors))
+	for i := range mirrors {
+		result[mirrors[i].PostID] = &mirrors[i]
+	}
+	return result, nil
+}
+
+// Database proxy methods for post mirrors
+func (d *Database) UpsertPostMirror(ctx context.Context, mirror *PostMirror) error {
+	return d.postMirrors.Upsert(ctx, mirror)
+}
+
+func (d *Database) GetPostMirror(ctx context.Context, id int) (*PostMirror, error) {
+	return d.postMirrors.GetByID(ctx, id)
+}
+
+func (d *Database) GetPostMirrorsByIDs(ctx context.Context, ids []int) (map[int]*PostMirror, error) {
+	return d.postMirrors.GetByIDs(ctx, ids)
+}
@@ -65,22 +65,55 @@ func (p *Posts) GetByID(ctx context.Context, id int) (*dto.PostInfo, error) {
 	}
 	return &post, nil
 }
-func (p *Posts) Search(ctx context.Context, filter bson.M) ([]dto.PostInfo, error) {
-	var rawPosts []bson.M
+func (p *Posts) GetByIDs(ctx context.Context, ids []int) (map[int]*dto.PostInfo, error) {
+	var rawPosts []struct {
+		ID  int    `bson:"_id"`
+		Doc bson.M `bson:",inline"`
+	}
+	err := p.coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}).
+		Select(bson.M{"_chartStats": 0}).
+		All(&rawPosts)
+	if err != nil {
+		return nil, err
+	}
+	posts := make(map[int]*dto.PostInfo, len(rawPosts))
+	for _, rawPost := range rawPosts {
+		var post dto.PostInfo
+		postBytes, _ := json.Marshal(rawPost.Doc)
+		if err := json.Unmarshal(postBytes, &post); err != nil {
+			return nil, err
+		}
+		posts[rawPost.ID] = &post
+	}
+	return posts, nil
+}
+
+// PostSearchResult 帖子搜索结果，在帖子信息前附加 id
+type PostSearchResult struct {
+	ID int `json:"id"`
+	dto.PostInfo
+}
+
+func (p *Posts) Search(ctx context.Context, filter bson.M, fields []string) ([]PostSearchResult, error) {
+	var rawPosts []struct {
+		ID  int    `bson:"_id"`
+		Doc bson.M `bson:",inline"`
+	}
 	err := p.coll.Find(ctx, filter).
-		Select(bson.M{"_id": 0, "_chartStats": 0}).
+		Select(searchSelect(fields, "_chartStats")).
 		All(&rawPosts)
 	if err != nil {
 		return nil, err
 	}
-	posts := make([]dto.PostInfo, len(rawPosts))
+	results := make([]PostSearchResult, len(rawPosts))
 	for i, rawPost := range rawPosts {
-		postBytes, _ := json.Marshal(rawPost)
-		if err := json.Unmarshal(postBytes, &posts[i]); err != nil {
+		postBytes, _ := json.Marshal(rawPost.Doc)
+		if err := json.Unmarshal(postBytes, &results[i].PostInfo); err != nil {
 			return nil, err
 		}
+		results[i].ID = rawPost.ID
 	}
-	return posts, nil
+	return results, nil
 }
 func (p *Posts) Delete(ctx context.Context, id int) error {
 	return p.coll.Remove(ctx, bson.M{"_id": id})
@@ -95,8 +128,12 @@ func (d *Database) GetPostByID(ctx context.Context, id int) (*dto.PostInfo, erro
 	return d.posts.GetByID(ctx, id)
 }
 
-func (d *Database) SearchPosts(ctx context.Context, filter bson.M) ([]dto.PostInfo, error) {
-	return d.posts.Search(ctx, filter)
+func (d *Database) GetPostsByIDs(ctx context.Context, ids []int) (map[int]*dto.PostInfo, error) {
+	return d.posts.GetByIDs(ctx, ids)
+}
+
+func (d *Database) SearchPosts(ctx context.Context, filter bson.M, fields []string) ([]PostSearchResult, error) {
+	return d.posts.Search(ctx, filter, fields)
 }
 
 func (d *Database) DeletePost(ctx context.Context, id int) error {
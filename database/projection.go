@@ -0,0 +1,18 @@
+package database
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// searchSelect 构建搜索投影，fields 为空时返回除内部字段外的完整文档
+func searchSelect(fields []string, internal ...string) bson.M {
+	sel := bson.M{}
+	if len(fields) == 0 {
+		for _, key := range internal {
+			sel[key] = 0
+		}
+		return sel
+	}
+	for _, field := range fields {
+		sel[field] = 1
+	}
+	return sel
+}
@@ -55,22 +55,55 @@ func (s *Songs) GetByID(ctx context.Context, id int) (*dto.SongInfo, error) {
 	}
 	return &song, nil
 }
-func (s *Songs) Search(ctx context.Context, filter bson.M) ([]dto.SongInfo, error) {
-	var rawSongs []bson.M
+func (s *Songs) GetByIDs(ctx context.Context, ids []int) (map[int]*dto.SongInfo, error) {
+	var rawSongs []struct {
+		ID  int    `bson:"_id"`
+		Doc bson.M `bson:",inline"`
+	}
+	err := s.coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}).
+		Select(bson.M{"_mainBPM": 0}).
+		All(&rawSongs)
+	if err != nil {
+		return nil, err
+	}
+	songs := make(map[int]*dto.SongInfo, len(rawSongs))
+	for _, rawSong := range rawSongs {
+		var song dto.SongInfo
+		songBytes, _ := json.Marshal(rawSong.Doc)
+		if err := json.Unmarshal(songBytes, &song); err != nil {
+			return nil, err
+		}
+		songs[rawSong.ID] = &song
+	}
+	return songs, nil
+}
+
+// SongSearchResult 歌曲搜索结果，在歌曲信息前附加 id
+type SongSearchResult struct {
+	ID int `json:"id"`
+	dto.SongInfo
+}
+
+func (s *Songs) Search(ctx context.Context, filter bson.M, fields []string) ([]SongSearchResult, error) {
+	var rawSongs []struct {
+		ID  int    `bson:"_id"`
+		Doc bson.M `bson:",inline"`
+	}
 	err := s.coll.Find(ctx, filter).
-		Select(bson.M{"_id": 0, "_mainBPM": 0}).
+		Select(searchSelect(fields, "_mainBPM")).
 		All(&rawSongs)
 	if err != nil {
 		return nil, err
 	}
-	songs := make([]dto.SongInfo, len(rawSongs))
+	results := make([]SongSearchResult, len(rawSongs))
 	for i, rawSong := range rawSongs {
-		songBytes, _ := json.Marshal(rawSong)
-		if err := json.Unmarshal(songBytes, &songs[i]); err != nil {
+		songBytes, _ := json.Marshal(rawSong.Doc)
+		if err := json.Unmarshal(songBytes, &results[i].SongInfo); err != nil {
 			return nil, err
 		}
+		results[i].ID = rawSong.ID
 	}
-	return songs, nil
+	return results, nil
 }
 func (s *Songs) Delete(ctx context.Context, id int) error {
 	return s.coll.Remove(ctx, bson.M{"_id": id})
@@ -124,8 +157,12 @@ func (d *Database) GetSongByID(ctx context.Context, id int) (*dto.SongInfo, erro
 	return d.songs.GetByID(ctx, id)
 }
 
-func (d *Database) SearchSongs(ctx context.Context, filter bson.M) ([]dto.SongInfo, error) {
-	return d.songs.Search(ctx, filter)
+func (d *Database) GetSongsByIDs(ctx context.Context, ids []int) (map[int]*dto.SongInfo, error) {
+	return d.songs.GetByIDs(ctx, ids)
+}
+
+func (d *Database) SearchSongs(ctx context.Context, filter bson.M, fields []string) ([]SongSearchResult, error) {
+	return d.songs.Search(ctx, filter, fields)
 }
 
 func (d *Database) DeleteSong(ctx context.Context, id int) error {
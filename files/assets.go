@@ -1,25 +1,63 @@
 package files
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
-const assetsPath = "data/assets/"
+// ErrInvalidAssetsName 资源名称不合法，例如包含 .. 或绝对路径
+var ErrInvalidAssetsName = errors.New("invalid assets name")
 
-func init() {
-	// 创建资源目录
-	err := createAssetsDir()
-	if err != nil {
-		return
+// CleanAssetsName 校验并规范化资源名称
+//
+// 参数：name - 相对于资源根目录的路径，使用 / 分隔
+//
+// 返回：规范化后的名称和错误信息，名称试图逃出资源根目录时返回 ErrInvalidAssetsName
+func CleanAssetsName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("assets name cannot be empty")
 	}
+	if strings.ContainsAny(name, "\\\x00") || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAssetsName, name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q", ErrInvalidAssetsName, name)
+		}
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." || filepath.IsAbs(cleaned) || filepath.VolumeName(cleaned) != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAssetsName, name)
+	}
+	return cleaned, nil
 }
 
-// createAssetsDir 创建资源目录
-func createAssetsDir() error {
-	if _, err := os.Stat(assetsPath); os.IsNotExist(err) {
-		return os.MkdirAll(assetsPath, 0755)
-	}
+// notExistError 资源不存在
+type notExistError struct {
+	name string
+}
+
+func (e *notExistError) Error() string {
+	return fmt.Sprintf("assets file %s does not exist", e.name)
+}
+
+func (e *notExistError) Unwrap() error {
+	return os.ErrNotExist
+}
+
+// nopSeekCloser 为 io.ReadSeeker 提供空的 Close 方法
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
 	return nil
 }
 
@@ -29,55 +67,140 @@ func createAssetsDir() error {
 //
 // 返回：文件内容的字节数据和错误信息
 func GetAssets(name string) ([]byte, error) {
-	if name == "" {
-		return nil, fmt.Errorf("assets name cannot be empty")
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return nil, err
 	}
 
-	// 首先尝试从内存缓存获取
-	if data, exists := getFromMemoryCache(name); exists {
-		return data, nil
-	}
+	cacheable := isAssetsCacheable(name)
 
-	// 从文件系统读取
-	filePath := assetsPath + name
-	if !fileExists(filePath) {
-		return nil, fmt.Errorf("assets file %s does not exist", name)
+	// 首先尝试从内存缓存获取
+	if cacheable {
+		if data, exists := getFromMemoryCache(assetsMemoryCache, name); exists {
+			return data, nil
+		}
 	}
 
-	data, err := readFileContent(filePath)
+	// 从存储后端读取
+	data, err := storage.Read(name)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &notExistError{name: name}
+		}
 		return nil, fmt.Errorf("failed to read assets file %s: %w", name, err)
 	}
 
 	// 更新内存缓存
-	updateMemoryCache(name, data)
+	if cacheable {
+		updateMemoryCache(assetsMemoryCache, name, data)
+	}
 
 	return data, nil
 }
 
+// OpenAssets 打开资源用于流式读取
+//
+// 参数：name - 文件名（含后缀）
+//
+// 返回：资源读取器、资源元信息和错误信息，资源不存在时错误满足 errors.Is(err, os.ErrNotExist)
+//
+// 允许进入内存缓存的小文件会经由内存缓存读取，其余文件直接从存储后端流式读取
+func OpenAssets(name string) (io.ReadSeekCloser, AssetsInfo, error) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return nil, AssetsInfo{}, err
+	}
+
+	info, err := storage.Stat(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, AssetsInfo{}, &notExistError{name: name}
+		}
+		return nil, AssetsInfo{}, fmt.Errorf("failed to stat assets file %s: %w", name, err)
+	}
+
+	if isAssetsCacheable(name) && assetsMemoryCache.accepts(info.Size) {
+		data, err := GetAssets(name)
+		if err != nil {
+			return nil, AssetsInfo{}, err
+		}
+		return nopSeekCloser{bytes.NewReader(data)}, info, nil
+	}
+
+	reader, err := storage.Open(name)
+	if err != nil {
+		return nil, AssetsInfo{}, fmt.Errorf("failed to open assets file %s: %w", name, err)
+	}
+	return reader, info, nil
+}
+
+// AssetsExists 检查资源是否存在，不读取资源内容
+//
+// 参数：name - 文件名（含后缀）
+//
+// 返回：资源是否存在和错误信息
+func AssetsExists(name string) (bool, error) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return false, err
+	}
+
+	if assetsMemoryCache.contains(name) {
+		return true, nil
+	}
+
+	return storage.Exists(name)
+}
+
+// AssetsURL 获取资源的临时访问链接
+//
+// 参数：name - 文件名（含后缀），expiry - 链接有效期
+//
+// 返回：访问链接和错误信息，存储后端不支持时返回 ErrPresignNotSupported
+func AssetsURL(name string, expiry time.Duration) (string, error) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.PresignURL(name, expiry)
+}
+
 // SaveAssets 保存数据到资源
 //
 // 参数：name - 文件名（含后缀），data - 要保存的字节数据
 //
 // 返回：错误信息
 func SaveAssets(name string, data []byte) error {
-	if name == "" {
-		return fmt.Errorf("assets name cannot be empty")
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return err
 	}
 
 	if data == nil {
 		return fmt.Errorf("data cannot be nil")
 	}
 
-	filePath := assetsPath + name
-
-	// 写入文件
-	if err := writeFileContent(filePath, data); err != nil {
+	// 写入存储后端
+	if err := storage.Write(name, data); err != nil {
 		return fmt.Errorf("failed to save assets file %s: %w", name, err)
 	}
 
-	// 更新内存缓存
-	updateMemoryCache(name, data)
+	// 移除旧的内存缓存，新数据在下次读取时再进入缓存
+	assetsMemoryCache.remove(name)
 
+	// 记录资源清单，变更延迟合并写回，批量下载结束与关闭时立即写回
+	manifest.set(name, newManifestEntry(data))
+
+	return nil
+}
+
+// deleteAssets 删除资源及其内存缓存与清单项
+func deleteAssets(name string) error {
+	if err := storage.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete assets file %s: %w", name, err)
+	}
+	assetsMemoryCache.remove(name)
+	manifest.remove(name)
 	return nil
 }
@@ -0,0 +1,121 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/pkg/log"
+)
+
+// AudioFormat 派生音频格式
+type AudioFormat string
+
+const (
+	AudioFormatMP3  AudioFormat = "mp3"
+	AudioFormatOpus AudioFormat = "opus"
+)
+
+var (
+	// ffmpegPath ffmpeg 可执行文件路径，为空时不支持转码
+	ffmpegPath string
+	// opusBitrate Opus 转码比特率
+	opusBitrate = "96k"
+	// previewOffset 试听片段起点
+	previewOffset = 60 * time.Second
+	// previewDuration 试听片段时长
+	previewDuration = 30 * time.Second
+	// audioEncodeTimeout 单次生成派生音频的超时时间
+	audioEncodeTimeout = 120 * time.Second
+)
+
+// configureAudio 根据配置设置试听片段参数与 ffmpeg
+func configureAudio(conf *config.AudioConfig) {
+	previewOffset = time.Duration(conf.PreviewOffset) * time.Second
+	previewDuration = time.Duration(conf.PreviewDuration) * time.Second
+	if previewDuration <= 0 {
+		previewDuration = 30 * time.Second
+	}
+	if conf.EncodeTimeout > 0 {
+		audioEncodeTimeout = time.Duration(conf.EncodeTimeout) * time.Second
+	}
+	if conf.OpusBitrate != "" {
+		opusBitrate = conf.OpusBitrate
+	}
+
+	ffmpegPath = ""
+	if conf.FFmpeg == "" {
+		return
+	}
+	p, err := exec.LookPath(conf.FFmpeg)
+	if err != nil {
+		log.Warnf("ffmpeg %s not found, opus variants disabled", conf.FFmpeg)
+		return
+	}
+	ffmpegPath = p
+}
+
+// AudioFormatSupported 检查是否能够生成该格式的派生音频
+func AudioFormatSupported(format AudioFormat) bool {
+	switch format {
+	case AudioFormatMP3:
+		return true
+	case AudioFormatOpus:
+		return ffmpegPath != ""
+	}
+	return false
+}
+
+// AudioVariantName 获取派生音频的资源名称
+//
+// 参数：name - 原音频资源名称，preview - 是否为试听片段，format - 音频格式
+//
+// 返回：派生音频的资源名称，例如 variants/sound/bgm001_preview.opus
+func AudioVariantName(name string, preview bool, format AudioFormat) string {
+	suffix := ""
+	if preview {
+		suffix = "_preview"
+	}
+	return variantName(name, suffix, string(format))
+}
+
+// EnsureAudioVariant 确保派生音频存在且不早于原音频，必要时从原音频生成
+//
+// 参数：name - 原 MP3 资源名称，preview - 是否为试听片段，format - 音频格式
+//
+// 返回：派生音频的资源名称和错误信息，原音频不存在时错误满足 errors.Is(err, os.ErrNotExist)
+//
+// 试听片段直接按 MP3 帧截取，转码为 Opus 需要 ffmpeg
+func EnsureAudioVariant(ctx context.Context, name string, preview bool, format AudioFormat) (string, error) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return "", err
+	}
+	if !AudioFormatSupported(format) {
+		return "", fmt.Errorf("audio format %s not supported", format)
+	}
+	if !preview && format == AudioFormatMP3 {
+		return name, nil
+	}
+
+	return ensureVariant(ctx, name, AudioVariantName(name, preview, format), audioEncodeTimeout, func(ctx context.Context, data []byte) ([]byte, error) {
+		if preview {
+			clip, err := cutMP3(data, previewOffset, previewDuration)
+			if err != nil {
+				return nil, err
+			}
+			data = clip
+		}
+		if format == AudioFormatMP3 {
+			return data, nil
+		}
+		return runEncoder(ctx, []string{
+			ffmpegPath, "-v", "error", "-y",
+			"-i", "{input}",
+			"-vn", "-c:a", "libopus", "-b:a", opusBitrate,
+			"{output}",
+		}, data, "mp3", string(format))
+	})
+}
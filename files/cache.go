@@ -2,26 +2,14 @@ package files
 
 import (
 	"fmt"
-	"os"
+	"path/filepath"
 )
 
-const cachePath = "data/cache/"
+// defaultCachePath 未配置 cache.path 时的缓存目录
+const defaultCachePath = "data/cache/"
 
-func init() {
-	// 创建缓存目录
-	err := createCacheDir()
-	if err != nil {
-		fmt.Printf("Failed to create cache directory: %v\n", err)
-	}
-}
-
-// createCacheDir 创建缓存目录
-func createCacheDir() error {
-	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-		return os.MkdirAll(cachePath, 0755)
-	}
-	return nil
-}
+// cachePath 当前使用的缓存目录，目录在 Init 时创建
+var cachePath = defaultCachePath
 
 // LoadCache 根据名称获取缓存数据
 //
@@ -36,12 +24,12 @@ func LoadCache(name string) ([]byte, error) {
 	}
 
 	// 首先尝试从内存缓存获取
-	if data, exists := getFromMemoryCache(name); exists {
+	if data, exists := getFromMemoryCache(cacheMemoryCache, name); exists {
 		return data, nil
 	}
 
 	// 从文件系统读取
-	filePath := cachePath + name
+	filePath := filepath.Join(cachePath, name)
 	if !fileExists(filePath) {
 		// 文件不存在，创建空文件作为初始化
 		emptyData := []byte{}
@@ -50,7 +38,7 @@ func LoadCache(name string) ([]byte, error) {
 		}
 
 		// 更新内存缓存
-		updateMemoryCache(name, emptyData)
+		updateMemoryCache(cacheMemoryCache, name, emptyData)
 
 		return emptyData, nil
 	}
@@ -61,7 +49,7 @@ func LoadCache(name string) ([]byte, error) {
 	}
 
 	// 更新内存缓存
-	updateMemoryCache(name, data)
+	updateMemoryCache(cacheMemoryCache, name, data)
 
 	return data, nil
 }
@@ -80,7 +68,7 @@ func SaveCache(name string, data []byte) error {
 		return fmt.Errorf("data cannot be nil")
 	}
 
-	filePath := cachePath + name
+	filePath := filepath.Join(cachePath, name)
 
 	// 写入文件
 	if err := writeFileContent(filePath, data); err != nil {
@@ -88,7 +76,7 @@ func SaveCache(name string, data []byte) error {
 	}
 
 	// 更新内存缓存
-	updateMemoryCache(name, data)
+	updateMemoryCache(cacheMemoryCache, name, data)
 
 	return nil
 }
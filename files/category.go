@@ -0,0 +1,132 @@
+package files
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// AssetsCategory 资源类别
+//
+// 每个类别对应资源根目录下的一个子目录，同时作为 /assets/:category 路由参数
+type AssetsCategory struct {
+	Name          string   // 类别名称（子目录名）
+	Description   string   // 类别说明
+	Exts          []string // 允许的文件后缀，为空时不限制
+	CacheControl  string   // 响应的 Cache-Control
+	ImageVariants bool     // 是否支持 ?size= 缩放与 Accept 格式协商
+	AudioVariants bool     // 是否支持 ?preview= 试听片段与 ?format= 转码
+}
+
+// 内置资源类别
+var (
+	CategoryMusicJacket = AssetsCategory{
+		Name:          "musicjacket",
+		Description:   "歌曲封面",
+		Exts:          []string{".png"},
+		CacheControl:  "public, max-age=3600",
+		ImageVariants: true,
+	}
+	CategorySound = AssetsCategory{
+		Name:          "sound",
+		Description:   "歌曲音频",
+		Exts:          []string{".mp3"},
+		CacheControl:  "public, max-age=3600",
+		AudioVariants: true,
+	}
+	CategoryBandLogo = AssetsCategory{
+		Name:         "bandlogo",
+		Description:  "乐队 Logo",
+		Exts:         []string{".png"},
+		CacheControl: "public, max-age=86400",
+	}
+	CategoryCharaIcon = AssetsCategory{
+		Name:         "charaicon",
+		Description:  "角色图标",
+		Exts:         []string{".png"},
+		CacheControl: "public, max-age=86400",
+	}
+	CategoryEventBanner = AssetsCategory{
+		Name:         "eventbanner",
+		Description:  "活动横幅",
+		Exts:         []string{".png"},
+		CacheControl: "public, max-age=86400",
+	}
+	CategoryCardThumb = AssetsCategory{
+		Name:         "cardthumb",
+		Description:  "卡牌缩略图",
+		Exts:         []string{".png"},
+		CacheControl: "public, max-age=86400",
+	}
+	CategoryPosts = AssetsCategory{
+		Name:         "posts",
+		Description:  "社区谱面自定义歌曲的音频与封面镜像，按帖子 ID 分目录存放",
+		Exts:         []string{".mp3", ".ogg", ".wav", ".m4a", ".aac", ".flac", ".png", ".jpg", ".webp", ".gif"},
+		CacheControl: "public, max-age=86400",
+	}
+)
+
+var (
+	categoriesMu sync.RWMutex
+	categories   = map[string]AssetsCategory{}
+)
+
+func init() {
+	for _, c := range []AssetsCategory{
+		CategoryMusicJacket,
+		CategorySound,
+		CategoryBandLogo,
+		CategoryCharaIcon,
+		CategoryEventBanner,
+		CategoryCardThumb,
+		CategoryPosts,
+	} {
+		RegisterAssetsCategory(c)
+	}
+}
+
+// RegisterAssetsCategory 注册资源类别，同名类别会被覆盖
+func RegisterAssetsCategory(c AssetsCategory) {
+	categoriesMu.Lock()
+	defer categoriesMu.Unlock()
+
+	categories[c.Name] = c
+}
+
+// GetAssetsCategory 根据名称获取资源类别
+func GetAssetsCategory(name string) (AssetsCategory, bool) {
+	categoriesMu.RLock()
+	defer categoriesMu.RUnlock()
+
+	c, ok := categories[name]
+	return c, ok
+}
+
+// AssetsCategories 获取所有已注册的资源类别，按名称排序
+func AssetsCategories() []AssetsCategory {
+	categoriesMu.RLock()
+	defer categoriesMu.RUnlock()
+
+	result := make([]AssetsCategory, 0, len(categories))
+	for _, c := range categories {
+		result = append(result, c)
+	}
+	slices.SortFunc(result, func(a, b AssetsCategory) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result
+}
+
+// Path 获取该类别下资源的完整名称
+func (c AssetsCategory) Path(name string) string {
+	return c.Name + "/" + name
+}
+
+// Allows 检查文件名后缀是否属于该类别
+func (c AssetsCategory) Allows(name string) bool {
+	if len(c.Exts) == 0 {
+		return true
+	}
+	return slices.Contains(c.Exts, strings.ToLower(path.Ext(name)))
+}
@@ -3,16 +3,37 @@ package files
 import (
 	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"sync"
+	"strings"
+
+	"anon-bestdori-database/config"
+)
+
+// 内存缓存默认容量
+const (
+	defaultAssetsMemoryCacheBytes = 64 << 20
+	defaultCacheMemoryCacheBytes  = 8 << 20
+	defaultMaxEntryBytes          = 2 << 20
 )
 
-// memoryCache 内存缓存，用于提高性能
+// assetsMemoryCache 资源内存缓存，cacheMemoryCache 缓存文件内存缓存，用于提高性能
 var (
-	memoryCache = make(map[string][]byte)
-	cacheMutex  sync.RWMutex
+	assetsMemoryCache = newLRUCache(defaultAssetsMemoryCacheBytes, defaultMaxEntryBytes)
+	cacheMemoryCache  = newLRUCache(defaultCacheMemoryCacheBytes, 0)
+	cacheAudioAssets  = false
 )
 
+// audioExts 音频资源后缀，默认不进入内存缓存
+var audioExts = map[string]bool{
+	".mp3":  true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+	".flac": true,
+	".m4a":  true,
+}
+
 // fileExists 检查文件是否存在
 func fileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
@@ -31,6 +52,8 @@ func readFileContent(filePath string) ([]byte, error) {
 }
 
 // writeFileContent 写入文件内容
+//
+// 先写入同目录下的临时文件，同步到磁盘后再重命名，避免中断时留下不完整的文件
 func writeFileContent(filePath string, data []byte) error {
 	// 确保目录存在
 	dir := filepath.Dir(filePath)
@@ -38,33 +61,64 @@ func writeFileContent(filePath string, data []byte) error {
 		return err
 	}
 
-	file, err := os.Create(filePath)
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+tempFileMarker+"*")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	tempPath := file.Name()
+	defer os.Remove(tempPath)
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, filePath)
+}
 
-	_, err = file.Write(data)
-	return err
+// tempFileMarker 临时文件名标记
+const tempFileMarker = ".tmp-"
+
+// isTempFile 检查是否为 writeFileContent 留下的临时文件
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempFileMarker)
 }
 
-// updateMemoryCache 更新内存缓存
-func updateMemoryCache(name string, data []byte) {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
+// configureMemoryCache 根据配置调整内存缓存容量
+func configureMemoryCache(conf *config.MemoryCacheConfig) {
+	assetsMemoryCache.resize(conf.AssetsMaxBytes, conf.MaxEntryBytes)
+	cacheMemoryCache.resize(conf.CacheMaxBytes, 0)
+	cacheAudioAssets = conf.CacheAudio
+}
+
+// isAssetsCacheable 检查资源是否允许进入内存缓存
+func isAssetsCacheable(name string) bool {
+	if cacheAudioAssets {
+		return true
+	}
+	return !audioExts[strings.ToLower(path.Ext(name))]
+}
 
+// updateMemoryCache 更新内存缓存
+func updateMemoryCache(c *lruCache, name string, data []byte) {
 	// 复制数据以避免外部修改影响缓存
 	cachedData := make([]byte, len(data))
 	copy(cachedData, data)
-	memoryCache[name] = cachedData
+	c.set(name, cachedData)
 }
 
 // getFromMemoryCache 从内存缓存获取数据
-func getFromMemoryCache(name string) ([]byte, bool) {
-	cacheMutex.RLock()
-	defer cacheMutex.RUnlock()
-
-	data, exists := memoryCache[name]
+func getFromMemoryCache(c *lruCache, name string) ([]byte, bool) {
+	data, exists := c.get(name)
 	if !exists {
 		return nil, false
 	}
@@ -74,3 +128,13 @@ func getFromMemoryCache(name string) ([]byte, bool) {
 	copy(result, data)
 	return result, true
 }
+
+// AssetsMemoryCacheStats 获取资源内存缓存统计信息
+func AssetsMemoryCacheStats() MemoryCacheStats {
+	return assetsMemoryCache.stats()
+}
+
+// CacheMemoryCacheStats 获取缓存文件内存缓存统计信息
+func CacheMemoryCacheStats() MemoryCacheStats {
+	return cacheMemoryCache.stats()
+}
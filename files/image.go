@@ -0,0 +1,156 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/pkg/log"
+)
+
+// ImageFormat 派生图片格式
+type ImageFormat string
+
+const (
+	ImageFormatPNG  ImageFormat = "png"
+	ImageFormatWebP ImageFormat = "webp"
+	ImageFormatAVIF ImageFormat = "avif"
+)
+
+var (
+	// imageSizes 允许的缩放尺寸（最长边像素）
+	imageSizes = []int{64, 128, 256, 512}
+	// imageEncoders 各格式的外部编码命令，{input} 与 {output} 会被替换为临时文件路径
+	imageEncoders = map[ImageFormat][]string{}
+	// imageEncodeTimeout 单次生成派生图片的超时时间
+	imageEncodeTimeout = 10 * time.Second
+)
+
+// configureImage 根据配置设置缩放尺寸与可用的编码命令
+func configureImage(conf *config.ImageConfig) {
+	imageSizes = slices.Clone(conf.Sizes)
+	if conf.EncodeTimeout > 0 {
+		imageEncodeTimeout = time.Duration(conf.EncodeTimeout) * time.Second
+	}
+	imageEncoders = map[ImageFormat][]string{}
+	for format, command := range map[ImageFormat][]string{
+		ImageFormatWebP: conf.WebPCommand,
+		ImageFormatAVIF: conf.AVIFCommand,
+	} {
+		if len(command) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(command[0]); err != nil {
+			log.Warnf("%s encoder %s not found, %s variants disabled", format, command[0], format)
+			continue
+		}
+		imageEncoders[format] = slices.Clone(command)
+	}
+}
+
+// ImageSizes 获取允许的缩放尺寸
+func ImageSizes() []int {
+	return slices.Clone(imageSizes)
+}
+
+// ImageSizeAllowed 检查缩放尺寸是否允许
+func ImageSizeAllowed(size int) bool {
+	return slices.Contains(imageSizes, size)
+}
+
+// ImageFormatSupported 检查是否能够生成该格式的派生图片
+func ImageFormatSupported(format ImageFormat) bool {
+	if format == ImageFormatPNG {
+		return true
+	}
+	_, ok := imageEncoders[format]
+	return ok
+}
+
+// ImageVariantName 获取派生图片的资源名称
+//
+// 参数：name - 原图资源名称，size - 最长边像素（0 为原尺寸），format - 图片格式
+//
+// 返回：派生图片的资源名称，例如 variants/musicjacket/xxx_128.webp
+func ImageVariantName(name string, size int, format ImageFormat) string {
+	suffix := ""
+	if size > 0 {
+		suffix = "_" + strconv.Itoa(size)
+	}
+	return variantName(name, suffix, string(format))
+}
+
+// EnsureImageVariant 确保派生图片存在且不早于原图，必要时从原图生成
+//
+// 参数：name - 原图资源名称，size - 最长边像素（0 为原尺寸），format - 图片格式
+//
+// 返回：派生图片的资源名称和错误信息，原图不存在时错误满足 errors.Is(err, os.ErrNotExist)，
+// 编码失败或超时时错误满足 errors.Is(err, ErrVariantFailed)
+func EnsureImageVariant(ctx context.Context, name string, size int, format ImageFormat) (string, error) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return "", err
+	}
+	if size != 0 && !ImageSizeAllowed(size) {
+		return "", fmt.Errorf("image size %d not allowed", size)
+	}
+	if !ImageFormatSupported(format) {
+		return "", fmt.Errorf("image format %s not supported", format)
+	}
+	if size == 0 && format == ImageFormatPNG && strings.EqualFold(path.Ext(name), ".png") {
+		return name, nil
+	}
+
+	return ensureVariant(ctx, name, ImageVariantName(name, size, format), imageEncodeTimeout, func(ctx context.Context, data []byte) ([]byte, error) {
+		return encodeImageVariant(ctx, data, size, format)
+	})
+}
+
+// encodeImageVariant 缩放图片并编码为指定格式
+func encodeImageVariant(ctx context.Context, data []byte, size int, format ImageFormat) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img = resizeImage(img, size)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if format == ImageFormatPNG {
+		return buf.Bytes(), nil
+	}
+	return runEncoder(ctx, imageEncoders[format], buf.Bytes(), "png", string(format))
+}
+
+// resizeImage 按最长边等比缩小图片，不放大
+func resizeImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if size <= 0 || (width <= size && height <= size) {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,165 @@
+package files
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryCacheStats 内存缓存统计信息
+type MemoryCacheStats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"maxBytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// lruEntry LRU 缓存条目
+type lruEntry struct {
+	name string
+	data []byte
+}
+
+// lruCache 按字节数限制容量的 LRU 内存缓存
+type lruCache struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	maxEntry  int64
+	bytes     int64
+	ll        *list.List
+	items     map[string]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// newLRUCache 创建 LRU 缓存
+//
+// maxBytes 为总容量，maxEntry 为单个条目的最大字节数，小于等于 0 表示不限制单个条目
+func newLRUCache(maxBytes, maxEntry int64) *lruCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		maxEntry: maxEntry,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// resize 调整容量限制，超出部分立即淘汰
+func (c *lruCache) resize(maxBytes, maxEntry int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes = maxBytes
+	c.maxEntry = maxEntry
+	for e := c.ll.Back(); e != nil; {
+		prev := e.Prev()
+		if ent := e.Value.(*lruEntry); !c.fits(int64(len(ent.data))) {
+			c.removeElement(e)
+			c.evictions++
+		}
+		e = prev
+	}
+	c.evict()
+}
+
+// fits 检查指定大小的条目是否允许缓存，调用方需持有锁
+func (c *lruCache) fits(size int64) bool {
+	if c.maxBytes <= 0 || size > c.maxBytes {
+		return false
+	}
+	return c.maxEntry <= 0 || size <= c.maxEntry
+}
+
+// accepts 加锁后检查指定大小的条目是否允许缓存
+func (c *lruCache) accepts(size int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.fits(size)
+}
+
+// get 获取缓存数据
+func (c *lruCache) get(name string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[name]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruEntry).data, true
+}
+
+// contains 检查缓存中是否存在条目，不影响统计与淘汰顺序
+func (c *lruCache) contains(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.items[name]
+	return ok
+}
+
+// set 写入缓存数据，超出单条目限制的数据不会被缓存
+func (c *lruCache) set(name string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[name]; ok {
+		c.removeElement(e)
+	}
+	if !c.fits(int64(len(data))) {
+		return
+	}
+
+	c.items[name] = c.ll.PushFront(&lruEntry{name: name, data: data})
+	c.bytes += int64(len(data))
+	c.evict()
+}
+
+// remove 删除缓存条目
+func (c *lruCache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[name]; ok {
+		c.removeElement(e)
+	}
+}
+
+// evict 淘汰最久未使用的条目直至不超过容量
+func (c *lruCache) evict() {
+	for c.bytes > c.maxBytes {
+		e := c.ll.Back()
+		if e == nil {
+			return
+		}
+		c.removeElement(e)
+		c.evictions++
+	}
+}
+
+func (c *lruCache) removeElement(e *list.Element) {
+	ent := c.ll.Remove(e).(*lruEntry)
+	delete(c.items, ent.name)
+	c.bytes -= int64(len(ent.data))
+}
+
+// stats 获取统计信息
+func (c *lruCache) stats() MemoryCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return MemoryCacheStats{
+		Entries:   c.ll.Len(),
+		Bytes:     c.bytes,
+		MaxBytes:  c.maxBytes,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
@@ -0,0 +1,293 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"anon-bestdori-database/pkg/log"
+)
+
+// manifestName 资源清单在资源根目录下的名称
+const manifestName = "manifest.json"
+
+// manifestFlushDelay 资源清单变更后延迟写回的时间，期间的变更合并为一次写回
+const manifestFlushDelay = 5 * time.Second
+
+// manifestWriteRetries 资源清单被其他实例同时修改时重新合并写回的次数
+const manifestWriteRetries = 5
+
+// isInternalAssets 检查是否为资源清单等内部使用的文件
+func isInternalAssets(name string) bool {
+	return name == manifestName || name == probeName
+}
+
+// ManifestEntry 资源清单中的一项
+type ManifestEntry struct {
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// assetsManifest 记录每个资源写入时的大小与校验和，用于发现损坏的资源
+//
+// 多个实例可能共享同一存储后端，写回时只合并本实例的变更，不覆盖其他实例写入的清单项
+type assetsManifest struct {
+	mu      sync.Mutex
+	flushMu sync.Mutex
+	entries map[string]ManifestEntry
+	pending map[string]*ManifestEntry // 尚未写回的变更，nil 表示移除
+	timer   *time.Timer               // 延迟写回的定时器，没有待写回的变更时为 nil
+}
+
+var manifest = &assetsManifest{
+	entries: map[string]ManifestEntry{},
+	pending: map[string]*ManifestEntry{},
+}
+
+// newManifestEntry 根据资源内容生成清单项
+func newManifestEntry(data []byte) ManifestEntry {
+	sum := sha256.Sum256(data)
+	return ManifestEntry{
+		Size:      int64(len(data)),
+		SHA256:    hex.EncodeToString(sum[:]),
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// hashAssets 流式读取存储中的资源并生成清单项
+func hashAssets(name string) (ManifestEntry, error) {
+	r, err := storage.Open(name)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	return ManifestEntry{
+		Size:      size,
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// readManifest 从存储后端读取资源清单，清单不存在时视为空清单
+//
+// 返回：清单项、清单的版本标识（存储后端不支持条件写入或清单不存在时为空）和错误信息
+func readManifest() (map[string]ManifestEntry, string, error) {
+	var (
+		data    []byte
+		version string
+		err     error
+	)
+	if cs, ok := storage.(ConditionalStorage); ok {
+		data, version, err = cs.ReadVersion(manifestName)
+	} else {
+		data, err = storage.Read(manifestName)
+	}
+
+	entries := map[string]ManifestEntry{}
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, "", err
+	}
+	return entries, version, nil
+}
+
+// applyManifestChanges 将变更应用到清单项
+func applyManifestChanges(entries map[string]ManifestEntry, changes map[string]*ManifestEntry) {
+	for name, entry := range changes {
+		if entry == nil {
+			delete(entries, name)
+		} else {
+			entries[name] = *entry
+		}
+	}
+}
+
+// load 从存储后端读取资源清单
+func (m *assetsManifest) load() error {
+	entries, _, err := readManifest()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = entries
+	m.pending = map[string]*ManifestEntry{}
+	return nil
+}
+
+func (m *assetsManifest) get(name string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[name]
+	return entry, ok
+}
+
+func (m *assetsManifest) set(name string, entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[name] = entry
+	m.pending[name] = &entry
+	m.scheduleFlush()
+}
+
+func (m *assetsManifest) remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entries[name]; ok {
+		delete(m.entries, name)
+		m.pending[name] = nil
+		m.scheduleFlush()
+	}
+}
+
+func (m *assetsManifest) snapshot() map[string]ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.entries)
+}
+
+// scheduleFlush 在 manifestFlushDelay 后写回资源清单，调用方需持有 mu
+func (m *assetsManifest) scheduleFlush() {
+	if m.timer != nil {
+		return
+	}
+	m.timer = time.AfterFunc(manifestFlushDelay, func() {
+		m.mu.Lock()
+		m.timer = nil
+		m.mu.Unlock()
+		if err := m.flush(); err != nil {
+			log.Errorf("failed to save assets manifest: %v", err)
+		}
+	})
+}
+
+// flush 将本实例的变更合并到存储后端中的资源清单
+func (m *assetsManifest) flush() error {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.mu.Lock()
+	if len(m.pending) == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	changes := m.pending
+	m.pending = map[string]*ManifestEntry{}
+	m.mu.Unlock()
+
+	entries, err := writeManifest(changes)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		// 写回失败时保留变更并稍后重试，期间产生的新变更优先
+		for name, entry := range changes {
+			if _, ok := m.pending[name]; !ok {
+				m.pending[name] = entry
+			}
+		}
+		m.scheduleFlush()
+		return err
+	}
+	// 采用合并后的清单，并保留写回期间产生的本地变更
+	applyManifestChanges(entries, m.pending)
+	m.entries = entries
+	return nil
+}
+
+// writeManifest 读取存储后端中的资源清单，应用变更后写回
+//
+// 存储后端支持条件写入时，清单在读取后被其他实例修改则重新读取合并
+//
+// 返回：写回的清单项和错误信息
+func writeManifest(changes map[string]*ManifestEntry) (map[string]ManifestEntry, error) {
+	for range manifestWriteRetries {
+		entries, version, err := readManifest()
+		if err != nil {
+			return nil, err
+		}
+		applyManifestChanges(entries, changes)
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return nil, err
+		}
+
+		cs, ok := storage.(ConditionalStorage)
+		if !ok {
+			return entries, storage.Write(manifestName, data)
+		}
+		err = cs.WriteIfVersion(manifestName, data, version)
+		if errors.Is(err, ErrVersionMismatch) {
+			continue
+		}
+		return entries, err
+	}
+	return nil, fmt.Errorf("assets manifest modified concurrently, gave up after %d attempts", manifestWriteRetries)
+}
+
+// GetManifestEntry 获取资源的清单项
+//
+// 参数：name - 文件名（含后缀）
+//
+// 返回：清单项和是否存在
+func GetManifestEntry(name string) (ManifestEntry, bool) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return ManifestEntry{}, false
+	}
+	return manifest.get(name)
+}
+
+// ManifestEntries 获取资源清单的副本
+func ManifestEntries() map[string]ManifestEntry {
+	return manifest.snapshot()
+}
+
+// FlushManifest 立即将资源清单写回存储后端
+func FlushManifest() error {
+	return manifest.flush()
+}
+
+// ListAssets 列出存储中的原始资源，不含资源清单与派生资源
+//
+// 返回：按名称排序的资源列表和错误信息
+func ListAssets() ([]AssetsObject, error) {
+	objects, err := storage.List()
+	if err != nil {
+		return nil, err
+	}
+	objects = slices.DeleteFunc(objects, func(obj AssetsObject) bool {
+		return isInternalAssets(obj.Name) || IsVariantName(obj.Name)
+	})
+	slices.SortFunc(objects, func(a, b AssetsObject) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return objects, nil
+}
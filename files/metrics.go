@@ -0,0 +1,64 @@
+package files
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// memoryCacheCollector 在抓取时读取内存缓存统计信息
+type memoryCacheCollector struct {
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	entries   *prometheus.Desc
+	bytes     *prometheus.Desc
+	maxBytes  *prometheus.Desc
+	hitRatio  *prometheus.Desc
+}
+
+func newMemoryCacheCollector() *memoryCacheCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("anon", "memory_cache", name), help, []string{"cache"}, nil)
+	}
+	return &memoryCacheCollector{
+		hits:      desc("hits_total", "Memory cache hits."),
+		misses:    desc("misses_total", "Memory cache misses."),
+		evictions: desc("evictions_total", "Memory cache evictions."),
+		entries:   desc("entries", "Entries currently held in the memory cache."),
+		bytes:     desc("bytes", "Bytes currently held in the memory cache."),
+		maxBytes:  desc("max_bytes", "Memory cache capacity in bytes."),
+		hitRatio:  desc("hit_ratio", "Memory cache hit ratio since startup."),
+	}
+}
+
+func (c *memoryCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.entries
+	ch <- c.bytes
+	ch <- c.maxBytes
+	ch <- c.hitRatio
+}
+
+func (c *memoryCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for cache, stats := range map[string]MemoryCacheStats{
+		"assets": AssetsMemoryCacheStats(),
+		"cache":  CacheMemoryCacheStats(),
+	} {
+		ratio := 0.0
+		if total := stats.Hits + stats.Misses; total > 0 {
+			ratio = float64(stats.Hits) / float64(total)
+		}
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), cache)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), cache)
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions), cache)
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries), cache)
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.Bytes), cache)
+		ch <- prometheus.MustNewConstMetric(c.maxBytes, prometheus.GaugeValue, float64(stats.MaxBytes), cache)
+		ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, ratio, cache)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(newMemoryCacheCollector())
+}
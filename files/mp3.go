@@ -0,0 +1,153 @@
+package files
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// mp3Frame MP3 音频帧
+type mp3Frame struct {
+	offset   int
+	size     int
+	duration time.Duration
+}
+
+// mp3 比特率表（kbps），按 [MPEG-1 / MPEG-2(.5)][Layer I / II / III] 索引
+var mp3Bitrates = [2][3][16]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// mp3SampleRates MPEG-1 采样率，MPEG-2 与 MPEG-2.5 分别为其 1/2 与 1/4
+var mp3SampleRates = [3]int{44100, 48000, 32000}
+
+// parseMP3FrameHeader 解析帧头，返回帧长度与时长
+func parseMP3FrameHeader(header []byte) (int, time.Duration, bool) {
+	if len(header) < 4 || header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return 0, 0, false
+	}
+	version := (header[1] >> 3) & 0x03 // 0: MPEG-2.5, 2: MPEG-2, 3: MPEG-1
+	layer := (header[1] >> 1) & 0x03   // 1: Layer III, 2: Layer II, 3: Layer I
+	bitrateIndex := header[2] >> 4
+	sampleRateIndex := (header[2] >> 2) & 0x03
+	padding := int(header[2]>>1) & 0x01
+	if version == 1 || layer == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return 0, 0, false
+	}
+
+	versionIndex := 0
+	sampleRate := mp3SampleRates[sampleRateIndex]
+	switch version {
+	case 2:
+		versionIndex = 1
+		sampleRate /= 2
+	case 0:
+		versionIndex = 1
+		sampleRate /= 4
+	}
+	layerIndex := 3 - int(layer)
+	bitrate := mp3Bitrates[versionIndex][layerIndex][bitrateIndex] * 1000
+
+	var samples, size int
+	switch layerIndex {
+	case 0:
+		samples = 384
+		size = (12*bitrate/sampleRate + padding) * 4
+	case 1:
+		samples = 1152
+		size = 144*bitrate/sampleRate + padding
+	default:
+		samples = 1152
+		if versionIndex == 1 {
+			samples = 576
+		}
+		size = samples/8*bitrate/sampleRate + padding
+	}
+	if size < 4 {
+		return 0, 0, false
+	}
+	return size, time.Duration(samples) * time.Second / time.Duration(sampleRate), true
+}
+
+// parseMP3Frames 解析 MP3 数据中的音频帧，跳过 ID3 标签与 Xing/Info/VBRI 信息帧
+func parseMP3Frames(data []byte) ([]mp3Frame, error) {
+	pos := 0
+	if len(data) >= 10 && bytes.HasPrefix(data, []byte("ID3")) {
+		size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+		pos = 10 + size
+		if data[5]&0x10 != 0 {
+			pos += 10
+		}
+	}
+
+	var frames []mp3Frame
+	for pos+4 <= len(data) {
+		if bytes.HasPrefix(data[pos:], []byte("TAG")) {
+			break
+		}
+		size, duration, ok := parseMP3FrameHeader(data[pos : pos+4])
+		if !ok || pos+size > len(data) {
+			pos++
+			continue
+		}
+		frame := data[pos : pos+size]
+		if len(frames) == 0 && isMP3InfoFrame(frame) {
+			pos += size
+			continue
+		}
+		frames = append(frames, mp3Frame{offset: pos, size: size, duration: duration})
+		pos += size
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no mp3 frames found")
+	}
+	return frames, nil
+}
+
+// isMP3InfoFrame 检查是否为不含音频的 Xing/Info/VBRI 信息帧
+func isMP3InfoFrame(frame []byte) bool {
+	head := frame[:min(len(frame), 64)]
+	return bytes.Contains(head, []byte("Xing")) ||
+		bytes.Contains(head, []byte("Info")) ||
+		bytes.Contains(head, []byte("VBRI"))
+}
+
+// cutMP3 按帧截取 MP3 片段，不重新编码
+//
+// 片段从 offset 开始，时长为 duration；音频不足时向前移动起点，保证片段尽量完整
+func cutMP3(data []byte, offset, duration time.Duration) ([]byte, error) {
+	frames, err := parseMP3Frames(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var total time.Duration
+	for _, frame := range frames {
+		total += frame.duration
+	}
+	offset = max(0, min(offset, total-duration))
+
+	var (
+		buf     bytes.Buffer
+		elapsed time.Duration
+	)
+	for _, frame := range frames {
+		if elapsed >= offset+duration {
+			break
+		}
+		if elapsed >= offset {
+			buf.Write(data[frame.offset : frame.offset+frame.size])
+		}
+		elapsed += frame.duration
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"anon-bestdori-database/config"
+)
+
+// S3Storage S3 兼容对象存储
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func NewS3Storage(conf *config.S3Config) (*S3Storage, error) {
+	if conf.Endpoint == "" || conf.Bucket == "" {
+		return nil, fmt.Errorf("s3 endpoint and bucket must be configured")
+	}
+
+	client, err := minio.New(conf.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.AccessKey, conf.SecretKey, ""),
+		Secure: conf.UseSSL,
+		Region: conf.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, conf.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check s3 bucket %s: %w", conf.Bucket, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("s3 bucket %s does not exist", conf.Bucket)
+	}
+
+	prefix := strings.Trim(conf.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3Storage{
+		client: client,
+		bucket: conf.Bucket,
+		prefix: prefix,
+	}, nil
+}
+
+func (s *S3Storage) key(name string) string {
+	return s.prefix + strings.TrimPrefix(name, "/")
+}
+
+// wrapError 将对象不存在的错误转换为 os.ErrNotExist
+func (s *S3Storage) wrapError(name string, err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return err
+}
+
+// quoteETag 为 S3 返回的 ETag 补全引号
+func quoteETag(etag string) string {
+	if etag != "" && !strings.HasPrefix(etag, `"`) {
+		return `"` + etag + `"`
+	}
+	return etag
+}
+
+func (s *S3Storage) Read(name string) ([]byte, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s.wrapError(name, err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, s.wrapError(name, err)
+	}
+	return data, nil
+}
+
+func (s *S3Storage) Write(name string, data []byte) error {
+	_, err := s.client.PutObject(
+		context.Background(),
+		s.bucket,
+		s.key(name),
+		bytes.NewReader(data),
+		int64(len(data)),
+		minio.PutObjectOptions{ContentType: mime.TypeByExtension(path.Ext(name))},
+	)
+	return err
+}
+
+func (s *S3Storage) ReadVersion(name string) ([]byte, string, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", s.wrapError(name, err)
+	}
+	defer obj.Close()
+
+	// 后续读取固定在 Stat 返回的版本上
+	info, err := obj.Stat()
+	if err != nil {
+		return nil, "", s.wrapError(name, err)
+	}
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, "", s.wrapError(name, err)
+	}
+	return data, info.ETag, nil
+}
+
+func (s *S3Storage) WriteIfVersion(name string, data []byte, version string) error {
+	opts := minio.PutObjectOptions{ContentType: mime.TypeByExtension(path.Ext(name))}
+	if version == "" {
+		opts.SetMatchETagExcept("*")
+	} else {
+		opts.SetMatchETag(strings.Trim(version, `"`))
+	}
+	_, err := s.client.PutObject(
+		context.Background(),
+		s.bucket,
+		s.key(name),
+		bytes.NewReader(data),
+		int64(len(data)),
+		opts,
+	)
+	switch minio.ToErrorResponse(err).Code {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return fmt.Errorf("%s: %w", name, ErrVersionMismatch)
+	}
+	return err
+}
+
+func (s *S3Storage) Exists(name string) (bool, error) {
+	_, err := s.client.StatObject(context.Background(), s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err != nil {
+		if err = s.wrapError(name, err); errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *S3Storage) Stat(name string) (AssetsInfo, error) {
+	info, err := s.client.StatObject(context.Background(), s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err != nil {
+		return AssetsInfo{}, s.wrapError(name, err)
+	}
+	return AssetsInfo{
+		Size:    info.Size,
+		ModTime: info.LastModified,
+		ETag:    quoteETag(info.ETag),
+	}, nil
+}
+
+func (s *S3Storage) Open(name string) (io.ReadSeekCloser, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s.wrapError(name, err)
+	}
+	return obj, nil
+}
+
+func (s *S3Storage) PresignURL(name string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(context.Background(), s.bucket, s.key(name), expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *S3Storage) List() ([]AssetsObject, error) {
+	var objects []AssetsObject
+	for obj := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{
+		Prefix:    s.prefix,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		objects = append(objects, AssetsObject{
+			Name: strings.TrimPrefix(obj.Key, s.prefix),
+			Info: AssetsInfo{
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+				ETag:    quoteETag(obj.ETag),
+			},
+		})
+	}
+	return objects, nil
+}
+
+func (s *S3Storage) Delete(name string) error {
+	return s.client.RemoveObject(context.Background(), s.bucket, s.key(name), minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,268 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/pkg/log"
+)
+
+// ErrPresignNotSupported 存储后端不支持生成临时访问链接
+var ErrPresignNotSupported = errors.New("storage backend does not support presigned URLs")
+
+// AssetsInfo 资源元信息
+type AssetsInfo struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// AssetsObject 存储后端中的一个资源
+type AssetsObject struct {
+	Name string
+	Info AssetsInfo
+}
+
+// Storage 资源存储后端
+//
+// name 为相对于资源根目录的路径，使用 / 分隔，例如 musicjacket/xxx.png
+type Storage interface {
+	// Read 读取资源，资源不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+	Read(name string) ([]byte, error)
+	// Write 写入资源
+	Write(name string, data []byte) error
+	// Exists 检查资源是否存在
+	Exists(name string) (bool, error)
+	// Stat 获取资源元信息
+	Stat(name string) (AssetsInfo, error)
+	// Open 打开资源用于流式读取
+	Open(name string) (io.ReadSeekCloser, error)
+	// PresignURL 生成资源的临时访问链接，不支持时返回 ErrPresignNotSupported
+	PresignURL(name string, expiry time.Duration) (string, error)
+	// List 列出所有资源
+	List() ([]AssetsObject, error)
+	// Delete 删除资源，资源不存在时不返回错误
+	Delete(name string) error
+}
+
+// ErrVersionMismatch 条件写入时资源已被修改
+var ErrVersionMismatch = errors.New("storage object version mismatch")
+
+// ConditionalStorage 支持条件写入的存储后端，多个实例共享资源清单时用于避免相互覆盖
+type ConditionalStorage interface {
+	// ReadVersion 读取资源及其版本标识
+	ReadVersion(name string) ([]byte, string, error)
+	// WriteIfVersion 仅在资源当前版本为 version 时写入，version 为空表示资源必须不存在
+	//
+	// 版本不一致时返回的错误满足 errors.Is(err, ErrVersionMismatch)
+	WriteIfVersion(name string, data []byte, version string) error
+}
+
+// defaultAssetsPath 未配置 assets.path 时文件系统后端的资源根目录
+const defaultAssetsPath = "data/assets/"
+
+// storage 当前使用的资源存储后端，目录在 Init 时创建
+var storage Storage = NewFileSystemStorage(defaultAssetsPath)
+
+// Init 根据配置初始化资源存储后端
+func Init(conf *config.Config) error {
+	configureMemoryCache(&conf.MemoryCache)
+	configureImage(&conf.Assets.Image)
+	configureAudio(&conf.Assets.Audio)
+
+	cachePath = conf.Cache.Path
+	if cachePath == "" {
+		cachePath = defaultCachePath
+	}
+	if err := os.MkdirAll(cachePath, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory %s: %w", cachePath, err)
+	}
+
+	switch conf.Assets.Backend {
+	case "", "filesystem":
+		root := conf.Assets.Path
+		if root == "" {
+			root = defaultAssetsPath
+		}
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return fmt.Errorf("failed to create assets directory %s: %w", root, err)
+		}
+		storage = NewFileSystemStorage(root)
+	case "s3":
+		s3Storage, err := NewS3Storage(&conf.Assets.S3)
+		if err != nil {
+			return err
+		}
+		storage = s3Storage
+	default:
+		return fmt.Errorf("unknown assets backend %q", conf.Assets.Backend)
+	}
+
+	if err := manifest.load(); err != nil {
+		log.Warnf("failed to load assets manifest, starting with an empty one: %v", err)
+	}
+	return nil
+}
+
+// probeName 可写性检查时写入的临时资源名称
+const probeName = ".probe"
+
+// writableCheckInterval 可写性检查结果的缓存时间
+const writableCheckInterval = 30 * time.Second
+
+// writableCheck 缓存的可写性检查结果
+var writableCheck struct {
+	mu        sync.Mutex
+	storage   Storage
+	checkedAt time.Time
+	err       error
+}
+
+// CheckWritable 检查存储后端是否可写
+//
+// 检查需要写入并删除临时资源，结果缓存 writableCheckInterval，存储后端切换后重新检查
+func CheckWritable() error {
+	writableCheck.mu.Lock()
+	defer writableCheck.mu.Unlock()
+
+	if writableCheck.storage == storage && time.Since(writableCheck.checkedAt) < writableCheckInterval {
+		return writableCheck.err
+	}
+	err := storage.Write(probeName, []byte(time.Now().UTC().Format(time.RFC3339)))
+	if err == nil {
+		err = storage.Delete(probeName)
+	}
+	writableCheck.storage = storage
+	writableCheck.checkedAt = time.Now()
+	writableCheck.err = err
+	return err
+}
+
+// GetStorage 获取当前使用的资源存储后端
+func GetStorage() Storage {
+	return storage
+}
+
+// FileSystemStorage 本地文件系统存储
+type FileSystemStorage struct {
+	root string
+}
+
+func NewFileSystemStorage(root string) *FileSystemStorage {
+	return &FileSystemStorage{root: root}
+}
+
+// path 将资源名称转换为文件路径，拒绝逃出根目录的名称
+func (s *FileSystemStorage) path(name string) (string, error) {
+	name, err := CleanAssetsName(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(name)), nil
+}
+
+func (s *FileSystemStorage) Read(name string) ([]byte, error) {
+	filePath, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return readFileContent(filePath)
+}
+
+func (s *FileSystemStorage) Write(name string, data []byte) error {
+	filePath, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return writeFileContent(filePath, data)
+}
+
+func (s *FileSystemStorage) Exists(name string) (bool, error) {
+	filePath, err := s.path(name)
+	if err != nil {
+		return false, err
+	}
+	return fileExists(filePath), nil
+}
+
+func (s *FileSystemStorage) Stat(name string) (AssetsInfo, error) {
+	filePath, err := s.path(name)
+	if err != nil {
+		return AssetsInfo{}, err
+	}
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return AssetsInfo{}, err
+	}
+	if fi.IsDir() {
+		return AssetsInfo{}, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return fileAssetsInfo(fi), nil
+}
+
+func fileAssetsInfo(fi os.FileInfo) AssetsInfo {
+	return AssetsInfo{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}
+}
+
+func (s *FileSystemStorage) Open(name string) (io.ReadSeekCloser, error) {
+	filePath, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+func (s *FileSystemStorage) PresignURL(string, time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+func (s *FileSystemStorage) List() ([]AssetsObject, error) {
+	var objects []AssetsObject
+	err := filepath.WalkDir(s.root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isTempFile(d.Name()) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, AssetsObject{
+			Name: filepath.ToSlash(rel),
+			Info: fileAssetsInfo(fi),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (s *FileSystemStorage) Delete(name string) error {
+	filePath, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"anon-bestdori-database/pkg/log"
+)
+
+// variantsDir 派生资源在资源根目录下的存放目录
+const variantsDir = "variants"
+
+// variantGroup 合并同一派生资源的并发生成
+var variantGroup singleflight.Group
+
+// variantRetryInterval 派生资源生成失败后再次尝试的间隔，期间直接返回 ErrVariantFailed
+const variantRetryInterval = 10 * time.Minute
+
+// variantFailures 最近生成失败的派生资源及失败时间
+var variantFailures sync.Map
+
+// ErrVariantFailed 派生资源生成失败，例如编码命令出错或超时，调用方可改为发送原资源
+var ErrVariantFailed = errors.New("failed to generate variant")
+
+// variantName 获取派生资源名称，例如 variants/musicjacket/xxx_128.webp
+func variantName(name, suffix, ext string) string {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	return path.Join(variantsDir, base+suffix+"."+ext)
+}
+
+// IsVariantName 检查资源名称是否属于从其他资源生成的派生资源
+func IsVariantName(name string) bool {
+	return strings.HasPrefix(name, variantsDir+"/")
+}
+
+// ensureVariant 确保派生资源存在且不早于原资源，必要时读取原资源调用 generate 生成
+//
+// 生成结果由等待同一派生资源的所有请求共享，不随单个请求取消而中止，timeout 大于 0 时限制生成时间
+func ensureVariant(ctx context.Context, name, variant string, timeout time.Duration, generate func(ctx context.Context, data []byte) ([]byte, error)) (string, error) {
+	source, err := storage.Stat(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", &notExistError{name: name}
+		}
+		return "", fmt.Errorf("failed to stat assets file %s: %w", name, err)
+	}
+	if info, err := storage.Stat(variant); err == nil && !info.ModTime.Before(source.ModTime) {
+		return variant, nil
+	}
+
+	if failedAt, ok := variantFailures.Load(variant); ok && time.Since(failedAt.(time.Time)) < variantRetryInterval {
+		return "", fmt.Errorf("%w %s: failed recently", ErrVariantFailed, variant)
+	}
+
+	ch := variantGroup.DoChan(variant, func() (any, error) {
+		genCtx := context.WithoutCancel(ctx)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			genCtx, cancel = context.WithTimeout(genCtx, timeout)
+			defer cancel()
+		}
+		data, err := GetAssets(name)
+		if err != nil {
+			return nil, err
+		}
+		generated, err := generate(genCtx, data)
+		if err != nil {
+			// 只在失败时记录一次日志，重试间隔内的请求不再调用编码命令
+			log.Warnf("failed to generate variant %s, retrying after %s: %v", variant, variantRetryInterval, err)
+			variantFailures.Store(variant, time.Now())
+			return nil, fmt.Errorf("%w %s: %w", ErrVariantFailed, variant, err)
+		}
+		variantFailures.Delete(variant)
+		return nil, SaveAssets(variant, generated)
+	})
+	select {
+	case result := <-ch:
+		if result.Err != nil {
+			return "", result.Err
+		}
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return variant, nil
+}
+
+// runEncoder 调用外部命令转换文件格式，命令参数中的 {input} 与 {output} 会被替换为临时文件路径
+//
+// ctx 取消或超时时终止命令
+func runEncoder(ctx context.Context, command []string, input []byte, inputExt, outputExt string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "anon-variant-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "input."+inputExt)
+	outputPath := filepath.Join(dir, "output."+outputExt)
+	if err := os.WriteFile(inputPath, input, 0644); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(command)-1)
+	for i, arg := range command[1:] {
+		arg = strings.ReplaceAll(arg, "{input}", inputPath)
+		args[i] = strings.ReplaceAll(arg, "{output}", outputPath)
+	}
+	cmd := exec.CommandContext(ctx, command[0], args...)
+	// 命令被终止后不再等待其子进程关闭输出
+	cmd.WaitDelay = time.Second
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("%s: %w", command[0], ctxErr)
+		}
+		return nil, fmt.Errorf("%s: %w: %s", command[0], err, bytes.TrimSpace(output))
+	}
+	return os.ReadFile(outputPath)
+}
@@ -0,0 +1,120 @@
+package files
+
+import (
+	"image/png"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// VerifyReport 资源校验结果
+type VerifyReport struct {
+	Checked int      // 校验的资源数量
+	Corrupt []string // 与清单不符或无法解析的资源，已删除
+	Missing []string // 清单中存在但存储中缺失的资源，清单项已移除
+	Adopted []string // 不在清单中但内容有效的资源，已补录
+	Objects []string // 校验后仍存在的资源
+}
+
+// VerifyAssets 重新扫描存储后端，按清单校验每个资源的大小与校验和
+//
+// 返回：校验结果和错误信息
+//
+// 损坏的资源会被删除以便重新下载，不在清单中的资源会在内容有效时补录进清单
+func VerifyAssets() (*VerifyReport, error) {
+	objects, err := storage.List()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{}
+	seen := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		if isInternalAssets(obj.Name) {
+			continue
+		}
+		seen[obj.Name] = true
+		report.Checked++
+
+		entry, tracked := manifest.get(obj.Name)
+		if tracked && entry.Size != obj.Info.Size {
+			report.Corrupt = append(report.Corrupt, obj.Name)
+			continue
+		}
+		if !tracked {
+			valid, err := validAssetsContent(obj.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !valid {
+				report.Corrupt = append(report.Corrupt, obj.Name)
+				continue
+			}
+		}
+
+		actual, err := hashAssets(obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tracked && (actual.Size != entry.Size || actual.SHA256 != entry.SHA256):
+			report.Corrupt = append(report.Corrupt, obj.Name)
+		case !tracked:
+			manifest.set(obj.Name, actual)
+			report.Adopted = append(report.Adopted, obj.Name)
+			report.Objects = append(report.Objects, obj.Name)
+		default:
+			report.Objects = append(report.Objects, obj.Name)
+		}
+	}
+
+	for _, name := range report.Corrupt {
+		if err := deleteAssets(name); err != nil {
+			return nil, err
+		}
+	}
+	for name := range manifest.snapshot() {
+		if !seen[name] {
+			manifest.remove(name)
+			report.Missing = append(report.Missing, name)
+		}
+	}
+	slices.Sort(report.Missing)
+
+	if err := FlushManifest(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// validAssetsContent 对没有清单项的资源做基本的格式检查
+//
+// 图片以流的方式解码，MP3 需要完整内容才能解析帧
+func validAssetsContent(name string) (bool, error) {
+	r, err := storage.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png":
+		_, err := png.Decode(r)
+		return err == nil, nil
+	case ".mp3":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return false, err
+		}
+		_, err = parseMP3Frames(data)
+		return err == nil, nil
+	}
+	if _, err := io.ReadFull(r, make([]byte, 1)); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
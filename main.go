@@ -29,16 +29,65 @@ import (
 	"anon-bestdori-database/config"
 )
 
-var initDatabase = flag.Bool("init-database", false, "初始化数据库")
+var (
+	configPath      = flag.String("config", "", "配置文件路径，未指定时使用环境变量 ANON_DATABASE_CONFIG 或当前目录下的 config.yaml")
+	printConfig     = flag.Bool("print-config", false, "输出合并环境变量与默认值后的有效配置（隐藏敏感信息）后退出")
+	writeConfig     = flag.String("write-config", "", "将默认配置写入指定文件作为配置模板后退出，不会覆盖已有文件")
+	initDatabase    = flag.Bool("init-database", false, "初始化数据库")
+	recordSnapshot  = flag.String("record-snapshot", "", "录制上游数据快照到指定目录后退出")
+	recordPostLimit = flag.Int("record-post-limit", 0, "录制快照时的最大帖子数量，0 表示不限制")
+	verifyAssets    = flag.Bool("verify-assets", false, "校验资源完整性，重新下载损坏或缺失的资源并报告孤立资源后退出")
+	addAPIKey       = flag.String("add-api-key", "", "生成指定名称的 API Key 并保存到数据库后退出")
+)
 
 func main() {
 	flag.Parse()
 
+	if *writeConfig != "" {
+		if err := config.WriteDefaults(*writeConfig); err != nil {
+			fmt.Printf("failed to write config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("default config written to %s\n", *writeConfig)
+		return
+	}
+
+	config.SetConfigFile(*configPath)
 	conf, err := config.Load()
 	if err != nil {
-		fmt.Printf("failed to load config: %v", err)
+		fmt.Printf("failed to load config: %v\n", err)
 		os.Exit(1)
 	}
+	config.Set(conf)
+
+	if *printConfig {
+		if err := config.Print(os.Stdout, conf); err != nil {
+			fmt.Printf("failed to print config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *recordSnapshot != "" {
+		if err := app.Record(conf, *recordSnapshot, *recordPostLimit); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *addAPIKey != "" {
+		if err := app.AddAPIKey(conf, *addAPIKey); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *verifyAssets {
+		if err := app.VerifyAssets(conf); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
 
 	app.Run(conf, *initDatabase)
 
@@ -50,10 +99,8 @@ func main() {
 		case sig := <-sigCh:
 			switch sig {
 			case syscall.SIGHUP:
-				if err := conf.Reload(); err != nil {
+				if err := app.Reload(); err != nil {
 					log.Errorf("failed to reload config: %v", err)
-				} else {
-					app.ReBoot(conf)
 				}
 			default:
 				log.Info("application stopping...")
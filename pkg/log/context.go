@@ -0,0 +1,35 @@
+package log
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDField 日志中请求 ID 的字段名
+const RequestIDField = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID 将请求 ID 附加到上下文
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 获取上下文中的请求 ID
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext 获取附带上下文中请求 ID 的日志条目
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logger.Logger)
+	if id := RequestID(ctx); id != "" {
+		entry = entry.WithField(RequestIDField, id)
+	}
+	return entry
+}
@@ -3,8 +3,10 @@ package log
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +36,7 @@ type Logger struct {
 	*logrus.Logger
 	Mutex      sync.Mutex
 	Level      LogLevel
+	name       string
 	lumberjack *lumberjack.Logger
 }
 
@@ -81,7 +84,7 @@ func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 			levelName = "UNKNOWN"
 		}
 		level := levelColor("[%s]", levelName)
-		return fmt.Appendf(nil, "%s %s\n", level, entry.Message), nil
+		return fmt.Appendf(nil, "%s %s%s\n", level, entry.Message, formatFields(entry.Data)), nil
 	}
 
 	loc := f.Location
@@ -125,42 +128,112 @@ func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	level := levelColor("[%s]", levelName)
 
 	// 组合日志消息
-	return []byte(fmt.Sprintf("%s %s: %s\n", timestamp, level, entry.Message)), nil
+	return []byte(fmt.Sprintf("%s %s: %s%s\n", timestamp, level, entry.Message, formatFields(entry.Data))), nil
+}
+
+// formatFields 将日志字段按键名排序格式化为 key=value
+func formatFields(data logrus.Fields) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		fmt.Fprintf(&b, " %s=%v", key, data[key])
+	}
+	return b.String()
+}
+
+// newFormatter 根据日志格式创建格式化器
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return &CustomFormatter{
+			TimestampFormat: "2006-01-02 15:04:05",
+			ForceColors:     true,
+			Location:        time.Local,
+		}, nil
+	case "json":
+		// 每行一个 JSON 对象，便于日志采集
+		return &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyTime: "timestamp",
+				logrus.FieldKeyMsg:  "message",
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("未知的日志格式: %s", format)
+	}
 }
 
 // Init 初始化日志系统
 func Init(conf *config.Config, logName string) error {
-	// 创建日志目录
-	if err := os.MkdirAll("log", 0o755); err != nil {
-		return fmt.Errorf("创建日志目录失败: %w", err)
+	logger.Mutex.Lock()
+	defer logger.Mutex.Unlock()
+
+	if logger.Logger == nil {
+		logger.Logger = logrus.New()
+	}
+	logger.name = logName
+	return logger.apply(conf)
+}
+
+// apply 应用日志格式、输出与级别配置，调用方需持有 Mutex
+func (l *Logger) apply(conf *config.Config) error {
+	formatter, err := newFormatter(conf.Log.Format)
+	if err != nil {
+		return err
 	}
 
-	logger.Logger = logrus.New()
-	logger.SetFormatter(&CustomFormatter{
-		TimestampFormat: "2006-01-02 15:04:05",
-		ForceColors:     true,
-		Location:        time.Local,
-	})
-
-	lumberjackLogger := &lumberjack.Logger{
-		Filename:   filepath.Join("log", logName+".log"),
-		MaxSize:    10, // MB
-		MaxBackups: 14,
-		MaxAge:     1, // days
-		Compress:   true,
-		LocalTime:  true,
+	var writers []io.Writer
+	if conf.Log.Stdout {
+		writers = append(writers, os.Stdout)
 	}
-	logger.lumberjack = lumberjackLogger
 
-	logger.SetOutput(io.MultiWriter(os.Stdout, lumberjackLogger))
+	var lumberjackLogger *lumberjack.Logger
+	if fileConf := conf.Log.File; fileConf.Enabled {
+		filename := fileConf.Path
+		if filename == "" {
+			filename = filepath.Join("log", l.name+".log")
+		}
+		// 创建日志目录
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return fmt.Errorf("创建日志目录失败: %w", err)
+		}
+		lumberjackLogger = &lumberjack.Logger{
+			Filename:   filename,
+			MaxSize:    fileConf.MaxSize,
+			MaxBackups: fileConf.MaxBackups,
+			MaxAge:     fileConf.MaxAge,
+			Compress:   fileConf.Compress,
+			LocalTime:  true,
+		}
+		writers = append(writers, lumberjackLogger)
+	}
+
+	l.SetFormatter(formatter)
+	switch len(writers) {
+	case 0:
+		l.SetOutput(io.Discard)
+	case 1:
+		l.SetOutput(writers[0])
+	default:
+		l.SetOutput(io.MultiWriter(writers...))
+	}
+
+	// 替换输出后关闭旧的日志文件
+	if l.lumberjack != nil {
+		_ = l.lumberjack.Close()
+	}
+	l.lumberjack = lumberjackLogger
 
 	// 设置级别
 	level, err := parseLogLevel(conf.Log.Level)
 	if err != nil {
 		return err
 	}
-	logger.Level = level
-	logger.SetLevel(convertLogLevel(level))
+	l.Level = level
+	l.SetLevel(convertLogLevel(level))
 	return nil
 }
 
@@ -211,10 +284,15 @@ func SetLogLevel(level LogLevel) {
 	logger.SetLevel(convertLogLevel(level))
 }
 
+// Reload 重新应用日志配置，无需重启进程
 func Reload(conf *config.Config) error {
-	level, _ := parseLogLevel(conf.Log.Level)
-	SetLogLevel(level)
-	return nil
+	logger.Mutex.Lock()
+	defer logger.Mutex.Unlock()
+
+	if logger.Logger == nil {
+		return fmt.Errorf("日志系统未初始化")
+	}
+	return logger.apply(conf)
 }
 
 // GetLogger 获取 Logger 对象
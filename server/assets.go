@@ -1,46 +1,244 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"anon-bestdori-database/config"
 	"anon-bestdori-database/database"
 	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func registerAssetsRoutes(group fiber.Router, _ *database.Database) {
-	// /assets/musicjacket/{assetsName}
-	group.Get("/musicjacket/:assetsName", func(c *fiber.Ctx) error {
+// assetsContentTypes 常见资源后缀对应的 Content-Type，优先于系统 MIME 表
+var assetsContentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".avif": "image/avif",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".json": "application/json",
+}
+
+func registerAssetsRoutes(group fiber.Router, db *database.Database) {
+	// /assets/manifest
+	group.Get("/manifest", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.AssetsManifest }, getAssetsManifestHandler(db))...)
+
+	// /assets/posts/{id}/{assetsName}
+	group.Get("/posts/:id/:assetsName", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil || id <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid post id " + c.Params("id"),
+			})
+		}
 		assetsName := c.Params("assetsName")
-		fullPath := "musicjacket/" + assetsName
+		if !files.CategoryPosts.Allows(assetsName) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "file type not allowed in assets category " + files.CategoryPosts.Name,
+			})
+		}
+		fullPath := files.CategoryPosts.Path(strconv.Itoa(id) + "/" + assetsName)
+		return sendAssets(c, fullPath, files.CategoryPosts.CacheControl)
+	})
 
-		data, err := files.GetAssets(fullPath)
-		if err != nil {
+	// /assets/{category}/{assetsName}
+	group.Get("/:category/:assetsName", func(c *fiber.Ctx) error {
+		category, ok := files.GetAssetsCategory(c.Params("category"))
+		if !ok {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "unknown assets category " + c.Params("category"),
+			})
+		}
+		assetsName := c.Params("assetsName")
+		if !category.Allows(assetsName) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "file type not allowed in assets category " + category.Name,
+			})
+		}
+		sourcePath := category.Path(assetsName)
+		fullPath, err := assetsVariant(c, category, sourcePath)
+		if errors.Is(err, files.ErrVariantFailed) {
+			// 派生资源生成失败时发送原资源，失败原因已在生成时记录
+			fullPath, err = sourcePath, nil
+		}
+		if err != nil {
+			status := fiber.StatusInternalServerError
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				status = fiber.StatusNotFound
+			case errors.Is(err, files.ErrInvalidAssetsName), errors.Is(err, errInvalidVariant):
+				status = fiber.StatusBadRequest
+			default:
+				log.FromContext(c.UserContext()).Errorf("failed to get variant of %s: %v", sourcePath, err)
+			}
+			return c.Status(status).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
-
-		// 假设 musicjacket 是图片
-		c.Set("Content-Type", "image/png")
-		c.Set("Cache-Control", "public, max-age=3600")
-		return c.Send(data)
+		return sendAssets(c, fullPath, category.CacheControl)
 	})
+}
 
-	// /assets/sound/{assetsName}
-	group.Get("/sound/:assetsName", func(c *fiber.Ctx) error {
-		assetsName := c.Params("assetsName")
-		fullPath := "sound/" + assetsName
+// errInvalidVariant 请求的派生资源参数不合法
+var errInvalidVariant = errors.New("invalid variant parameter")
 
-		data, err := files.GetAssets(fullPath)
-		if err != nil {
+// assetsVariant 根据类别与请求参数获取实际发送的资源名称，无需派生时返回原名称
+func assetsVariant(c *fiber.Ctx, category files.AssetsCategory, fullPath string) (string, error) {
+	switch {
+	case category.ImageVariants:
+		c.Vary(fiber.HeaderAccept)
+		return imageVariant(c, fullPath)
+	case category.AudioVariants:
+		return audioVariant(c, fullPath)
+	}
+	return fullPath, nil
+}
+
+// imageVariant 根据 ?size= 与 Accept 获取派生图片名称
+func imageVariant(c *fiber.Ctx, fullPath string) (string, error) {
+	size := 0
+	if s := c.Query("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || !files.ImageSizeAllowed(n) {
+			return "", fmt.Errorf("%w: size %q, allowed sizes: %v", errInvalidVariant, s, files.ImageSizes())
+		}
+		size = n
+	}
+	format := negotiateImageFormat(c.Get(fiber.HeaderAccept))
+	if size == 0 && format == files.ImageFormatPNG {
+		return fullPath, nil
+	}
+	return files.EnsureImageVariant(c.UserContext(), fullPath, size, format)
+}
+
+// audioVariant 根据 ?preview= 与 ?format= 获取派生音频名称
+func audioVariant(c *fiber.Ctx, fullPath string) (string, error) {
+	preview := c.QueryBool("preview")
+	format := files.AudioFormat(strings.ToLower(c.Query("format", string(files.AudioFormatMP3))))
+	if !files.AudioFormatSupported(format) {
+		return "", fmt.Errorf("%w: format %q not supported", errInvalidVariant, format)
+	}
+	if !preview && format == files.AudioFormatMP3 {
+		return fullPath, nil
+	}
+	return files.EnsureAudioVariant(c.UserContext(), fullPath, preview, format)
+}
+
+// negotiateImageFormat 根据 Accept 选择图片格式，仅在客户端明确声明支持时返回 AVIF 或 WebP
+func negotiateImageFormat(accept string) files.ImageFormat {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(mediaType))] = q > 0
+	}
+
+	for _, format := range []files.ImageFormat{files.ImageFormatAVIF, files.ImageFormatWebP} {
+		if accepted["image/"+string(format)] && files.ImageFormatSupported(format) {
+			return format
+		}
+	}
+	return files.ImageFormatPNG
+}
+
+// sendAssets 发送资源，存储后端支持时可重定向到临时访问链接
+func sendAssets(c *fiber.Ctx, fullPath, cacheControl string) error {
+	fullPath, err := files.CleanAssetsName(fullPath)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if conf := requestConfig(c); conf.Assets.Redirect {
+		expiry := time.Duration(conf.Assets.PresignExpiry) * time.Second
+		if expiry <= 0 {
+			expiry = time.Hour
+		}
+		url, err := files.AssetsURL(fullPath, expiry)
+		if err == nil {
+			return c.Redirect(url, fiber.StatusFound)
+		}
+		if !errors.Is(err, files.ErrPresignNotSupported) {
+			log.FromContext(c.UserContext()).Errorf("failed to presign assets %s: %v", fullPath, err)
+		}
+	}
+
+	reader, info, err := files.OpenAssets(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
+		log.FromContext(c.UserContext()).Errorf("failed to open assets %s: %v", fullPath, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-		// 假设 sound 是音频文件
-		c.Set("Content-Type", "audio/mp3")
-		c.Set("Cache-Control", "public, max-age=3600")
-		return c.Send(data)
-	})
+	contentType, err := detectContentType(fullPath, reader)
+	if err != nil {
+		reader.Close()
+		log.FromContext(c.UserContext()).Errorf("failed to detect content type of %s: %v", fullPath, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendStream(c, reader, info, contentType, cacheControl)
+}
+
+// contentTypeByExt 根据后缀确定资源类型，无法识别时返回空字符串
+func contentTypeByExt(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := assetsContentTypes[ext]; ok {
+		return contentType
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// detectContentType 根据后缀确定资源类型，无法识别时读取文件头嗅探
+func detectContentType(name string, reader io.ReadSeeker) (string, error) {
+	if contentType := contentTypeByExt(name); contentType != "" {
+		return contentType, nil
+	}
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(reader, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
 }
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/database"
+	"anon-bestdori-database/pkg/log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// apiKeyCacheTTL 数据库中 API Key 查询结果的缓存时间，不存在的密钥同样缓存
+const apiKeyCacheTTL = time.Minute
+
+// maxAPIKeyCache API Key 缓存的最大条目数，超出时淘汰最早写入的条目
+const maxAPIKeyCache = 10000
+
+// apiKeyLookupLimit 每个 IP 每个限流窗口内允许的数据库 API Key 查询次数
+//
+// 在校验密钥前生效，避免使用随机密钥绕过 IP 限流并压垮数据库
+const apiKeyLookupLimit = 30
+
+// anonymousClient 未携带 API Key 的请求在统计中的名称
+const anonymousClient = "anonymous"
+
+var (
+	authRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "auth",
+		Name:      "requests_total",
+		Help:      "Requests accepted by the auth middleware by route group and API key name.",
+	}, []string{"group", "client"})
+	authRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "auth",
+		Name:      "rejected_total",
+		Help:      "Requests rejected by the auth middleware by route group and reason.",
+	}, []string{"group", "reason"})
+)
+
+// cachedAPIKey 数据库 API Key 查询缓存，apiKey 为 nil 表示密钥不存在
+type cachedAPIKey struct {
+	apiKey  *database.APIKey
+	expires time.Time
+}
+
+// cacheExpiry 缓存条目的写入顺序，缓存时间固定，写入顺序即过期顺序
+type cacheExpiry struct {
+	hash    string
+	expires time.Time
+}
+
+// authenticator 校验 API Key 并按路由组限流
+type authenticator struct {
+	db      *database.Database
+	limiter *rateLimiter
+
+	mu     sync.Mutex
+	cache  map[string]cachedAPIKey
+	expiry []cacheExpiry
+}
+
+func newAuthenticator(db *database.Database) *authenticator {
+	return &authenticator{
+		db:      db,
+		limiter: newRateLimiter(),
+		cache:   map[string]cachedAPIKey{},
+	}
+}
+
+// routeConfig 获取路由组的认证与限流配置
+func routeConfig(auth config.AuthConfig, group string) config.RouteAuthConfig {
+	switch group {
+	case "posts":
+		return auth.Posts
+	case "songs":
+		return auth.Songs
+	case "charts":
+		return auth.Charts
+	case "assets":
+		return auth.Assets
+	}
+	return config.RouteAuthConfig{}
+}
+
+// requestAPIKey 从 X-API-Key 或 Authorization: Bearer 请求头获取 API Key
+func requestAPIKey(c *fiber.Ctx) string {
+	if key := c.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// configKey 匹配配置中的密钥
+func configKey(auth config.AuthConfig, key string) *database.APIKey {
+	for _, entry := range auth.Keys {
+		name, configKey, ok := strings.Cut(entry, ":")
+		if ok && subtle.ConstantTimeCompare([]byte(configKey), []byte(key)) == 1 {
+			return &database.APIKey{Hash: database.HashAPIKey(key), Name: name}
+		}
+	}
+	return nil
+}
+
+// cached 获取缓存的数据库查询结果
+func (a *authenticator) cached(hash string) (*database.APIKey, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.cache[hash]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.apiKey, true
+}
+
+// store 缓存数据库查询结果，并按写入顺序淘汰过期或超出容量的条目
+func (a *authenticator) store(hash string, apiKey *database.APIKey) {
+	now := time.Now()
+	expires := now.Add(apiKeyCacheTTL)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache[hash] = cachedAPIKey{apiKey: apiKey, expires: expires}
+	a.expiry = append(a.expiry, cacheExpiry{hash: hash, expires: expires})
+
+	for len(a.expiry) > 0 && (now.After(a.expiry[0].expires) || len(a.cache) > maxAPIKeyCache) {
+		oldest := a.expiry[0]
+		a.expiry[0] = cacheExpiry{}
+		a.expiry = a.expiry[1:]
+		// 同一密钥重新写入后旧的顺序记录不再对应缓存条目
+		if entry, ok := a.cache[oldest.hash]; ok && entry.expires.Equal(oldest.expires) {
+			delete(a.cache, oldest.hash)
+		}
+	}
+}
+
+// known 从配置中的密钥与缓存查找 API Key，无需查询数据库
+//
+// 返回：API Key（不存在时为 nil）和是否已确定结果
+func (a *authenticator) known(auth config.AuthConfig, key string) (*database.APIKey, bool) {
+	if apiKey := configKey(auth, key); apiKey != nil {
+		return apiKey, true
+	}
+	if a.db == nil {
+		return nil, true
+	}
+	return a.cached(database.HashAPIKey(key))
+}
+
+// fetch 从数据库查询 API Key 并缓存结果
+func (a *authenticator) fetch(c *fiber.Ctx, key string) (*database.APIKey, error) {
+	apiKey, err := a.db.GetAPIKey(c.UserContext(), key)
+	if err != nil {
+		return nil, err
+	}
+	a.store(database.HashAPIKey(key), apiKey)
+	return apiKey, nil
+}
+
+// tooManyRequests 设置限流响应头并返回 429
+func tooManyRequests(c *fiber.Ctx, group string, limit int, reset time.Time) error {
+	authRejected.WithLabelValues(group, "rate_limited").Inc()
+	retryAfter := max(int(time.Until(reset).Seconds()+0.999), 1)
+	c.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	c.Set("X-RateLimit-Remaining", "0")
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"result": false, "error": "请求过于频繁"})
+}
+
+// middleware 创建路由组的认证与限流中间件
+func (a *authenticator) middleware(group string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth := requestConfig(c).Server.Auth
+		if !auth.Enabled {
+			return c.Next()
+		}
+		route := routeConfig(auth, group)
+
+		window := time.Duration(auth.Window) * time.Second
+		client := anonymousClient
+		bucket := "ip:" + c.IP()
+		limit := route.IPLimit
+
+		if key := requestAPIKey(c); key != "" {
+			apiKey, ok := a.known(auth, key)
+			if !ok {
+				// 未知的密钥需要查询数据库，先按 IP 限制查询次数，避免使用随机密钥绕过 IP 限流
+				allowed, _, reset := a.limiter.allow("lookup:ip:"+c.IP(), apiKeyLookupLimit, window)
+				if !allowed {
+					return tooManyRequests(c, group, apiKeyLookupLimit, reset)
+				}
+				var err error
+				if apiKey, err = a.fetch(c, key); err != nil {
+					log.FromContext(c.UserContext()).Errorf("failed to look up api key: %v", err)
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": "API Key 校验失败"})
+				}
+			}
+			if apiKey == nil || apiKey.Disabled {
+				authRejected.WithLabelValues(group, "invalid_key").Inc()
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"result": false, "error": "无效的 API Key"})
+			}
+			client = apiKey.Name
+			bucket = "key:" + apiKey.Hash
+			limit = route.KeyLimit
+			if apiKey.RateLimit > 0 {
+				limit = apiKey.RateLimit
+			}
+		} else if route.RequireKey {
+			authRejected.WithLabelValues(group, "missing_key").Inc()
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"result": false, "error": "缺少 API Key"})
+		}
+
+		if limit > 0 {
+			allowed, remaining, reset := a.limiter.allow(group+":"+bucket, limit, window)
+			if !allowed {
+				return tooManyRequests(c, group, limit, reset)
+			}
+			c.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+
+		authRequests.WithLabelValues(group, client).Inc()
+		return c.Next()
+	}
+}
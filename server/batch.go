@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBatchIDs 单次批量查询的 ID 数量上限
+const maxBatchIDs = 100
+
+type batchRequest struct {
+	IDs []any `json:"ids"`
+}
+
+// parseBatchIDs 读取 GET ?ids=1,2,3 或 POST {"ids": [1, 2, 3]} 中的 ID 列表，去重并保持顺序
+func parseBatchIDs(c *fiber.Ctx) ([]string, error) {
+	var raw []string
+	if c.Method() == fiber.MethodPost {
+		var req batchRequest
+		if err := json.Unmarshal(c.Body(), &req); err != nil {
+			return nil, fmt.Errorf("无效的请求体")
+		}
+		for _, id := range req.IDs {
+			switch v := id.(type) {
+			case string:
+				raw = append(raw, v)
+			case float64:
+				raw = append(raw, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				return nil, fmt.Errorf("无效的 ID 格式: %v", id)
+			}
+		}
+	} else {
+		raw = strings.Split(c.Query("ids"), ",")
+	}
+
+	ids := make([]string, 0, len(raw))
+	for _, id := range raw {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("缺少 ID")
+	}
+	if len(ids) > maxBatchIDs {
+		return nil, fmt.Errorf("ID 数量超过上限 %d", maxBatchIDs)
+	}
+	return ids, nil
+}
+
+// parseBatchIntIDs 解析数字 ID，返回的字符串 ID 已规范化并去重
+func parseBatchIntIDs(c *fiber.Ctx) ([]int, []string, error) {
+	raw, err := parseBatchIDs(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []int
+	var keys []string
+	for _, s := range raw {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的 ID 格式: %s", s)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+			keys = append(keys, strconv.Itoa(id))
+		}
+	}
+	return ids, keys, nil
+}
+
+// batchResponse 按 ID 组装批量查询结果，未找到的 ID 标记为 result: false
+func batchResponse[K comparable, V any](keys []string, ids []K, docs map[K]*V, name, notFound string) fiber.Map {
+	items := make(fiber.Map, len(ids))
+	count := 0
+	for i, id := range ids {
+		doc, ok := docs[id]
+		if !ok {
+			items[keys[i]] = fiber.Map{"result": false, "error": notFound}
+			continue
+		}
+		items[keys[i]] = fiber.Map{"result": true, name: doc}
+		count++
+	}
+	return fiber.Map{
+		"result":   true,
+		"count":    count,
+		name + "s": items,
+	}
+}
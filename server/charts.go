@@ -1,13 +1,17 @@
 package server
 
 import (
+	"anon-bestdori-database/config"
 	"anon-bestdori-database/database"
+	"anon-bestdori-database/pkg/log"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func registerChartsRoutes(router fiber.Router, db *database.Database) {
-	router.Get("/:songId/:diff", getChartsIdDiffHandler(db))
+	router.Get("/batch", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.Charts }, getChartsBatchHandler(db))...)
+	router.Post("/batch", uncachedJSONHandlers(getChartsBatchHandler(db))...)
+	router.Get("/:songId/:diff", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.Charts }, getChartsIdDiffHandler(db))...)
 }
 
 func getChartsIdDiffHandler(db *database.Database) fiber.Handler {
@@ -16,8 +20,9 @@ func getChartsIdDiffHandler(db *database.Database) fiber.Handler {
 		diff := c.Params("diff")
 		id := songId + "-" + diff
 
-		chart, err := db.GetChartByID(c.Context(), id)
+		chart, err := db.GetChartByID(c.UserContext(), id)
 		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to get chart %s: %v", id, err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
 		}
 		if chart == nil {
@@ -30,3 +35,21 @@ func getChartsIdDiffHandler(db *database.Database) fiber.Handler {
 		})
 	}
 }
+
+// getChartsBatchHandler 批量获取谱面，ID 形如 {songId}-{diff}
+func getChartsBatchHandler(db *database.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ids, err := parseBatchIDs(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+
+		charts, err := db.GetChartsByIDs(c.UserContext(), ids)
+		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to get charts %v: %v", ids, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+
+		return c.JSON(batchResponse(ids, ids, charts, "chart", "谱面未找到"))
+	}
+}
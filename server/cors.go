@@ -0,0 +1,118 @@
+package server
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"anon-bestdori-database/config"
+	"anon-bestdori-database/version"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// corsPolicy 根据请求路径所属的路由组获取跨域策略，路由组策略中未设置的项沿用默认策略
+func corsPolicy(conf *config.Config, path string) config.CORSPolicyConfig {
+	cors := conf.Server.CORS
+	group, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+
+	var policy config.CORSPolicyConfig
+	switch group {
+	case "posts":
+		policy = cors.Posts
+	case "songs":
+		policy = cors.Songs
+	case "charts":
+		policy = cors.Charts
+	case "assets":
+		policy = cors.Assets
+	}
+	if len(policy.AllowOrigins) == 0 {
+		return cors.Default
+	}
+	if len(policy.AllowMethods) == 0 {
+		policy.AllowMethods = cors.Default.AllowMethods
+	}
+	if len(policy.AllowHeaders) == 0 {
+		policy.AllowHeaders = cors.Default.AllowHeaders
+	}
+	if len(policy.ExposeHeaders) == 0 {
+		policy.ExposeHeaders = cors.Default.ExposeHeaders
+	}
+	if policy.MaxAge == 0 {
+		policy.MaxAge = cors.Default.MaxAge
+	}
+	return policy
+}
+
+// matchOrigin 检查来源是否匹配允许的来源，支持 https://*.example.com 形式的子域名通配
+func matchOrigin(origin, pattern string) bool {
+	if pattern == "*" || strings.EqualFold(origin, pattern) {
+		return true
+	}
+	prefix, suffix, ok := strings.Cut(pattern, "*.")
+	if !ok {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	prefix, suffix = strings.ToLower(prefix), "."+strings.ToLower(suffix)
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix) &&
+		!strings.Contains(origin[len(prefix):len(origin)-len(suffix)], "/")
+}
+
+// corsMiddleware 按请求所属路由组的跨域策略设置响应头并处理预检请求
+func corsMiddleware(c *fiber.Ctx) error {
+	c.Set("Server-Version", "Anon-Database/"+version.Version)
+
+	policy := corsPolicy(requestConfig(c), c.Path())
+	origin := c.Get(fiber.HeaderOrigin)
+	preflight := c.Method() == fiber.MethodOptions
+
+	// 仅允许任意来源且不携带凭据时响应与来源无关，其余情况需按来源区分缓存
+	wildcard := !policy.AllowCredentials && slices.Contains(policy.AllowOrigins, "*")
+	if !wildcard {
+		c.Vary(fiber.HeaderOrigin)
+	}
+
+	allowed := origin != "" && slices.ContainsFunc(policy.AllowOrigins, func(pattern string) bool {
+		return matchOrigin(origin, pattern)
+	})
+	if allowed {
+		if wildcard {
+			c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+		} else {
+			c.Set(fiber.HeaderAccessControlAllowOrigin, origin)
+		}
+		if policy.AllowCredentials {
+			c.Set(fiber.HeaderAccessControlAllowCredentials, "true")
+		}
+		if !preflight && len(policy.ExposeHeaders) > 0 {
+			c.Set(fiber.HeaderAccessControlExposeHeaders, strings.Join(policy.ExposeHeaders, ", "))
+		}
+	}
+
+	if !preflight {
+		return c.Next()
+	}
+
+	if allowed {
+		c.Vary(fiber.HeaderAccessControlRequestMethod, fiber.HeaderAccessControlRequestHeaders)
+		if len(policy.AllowMethods) > 0 {
+			c.Set(fiber.HeaderAccessControlAllowMethods, strings.Join(policy.AllowMethods, ", "))
+		}
+		headers := strings.Join(policy.AllowHeaders, ", ")
+		// 携带凭据时 * 不被浏览器视为通配，改为回显请求的请求头
+		if policy.AllowCredentials && slices.Contains(policy.AllowHeaders, "*") {
+			headers = c.Get(fiber.HeaderAccessControlRequestHeaders)
+		}
+		if headers != "" {
+			c.Set(fiber.HeaderAccessControlAllowHeaders, headers)
+		}
+		if policy.MaxAge > 0 {
+			c.Set(fiber.HeaderAccessControlMaxAge, strconv.Itoa(policy.MaxAge))
+		}
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
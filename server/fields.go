@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"anon-bestdori-database/database"
+)
+
+// searchView 搜索接口可投影的字段与默认摘要视图
+type searchView struct {
+	fields  []string
+	summary []string
+}
+
+// newSearchView 由搜索结果类型的 json 标签得到可投影的字段，随数据结构自动更新
+func newSearchView(result any, summary ...string) searchView {
+	v := searchView{fields: jsonFields(reflect.TypeOf(result)), summary: summary}
+	for _, field := range summary {
+		if !slices.Contains(v.fields, field) {
+			panic(fmt.Sprintf("summary field %s not found in %T", field, result))
+		}
+	}
+	return v
+}
+
+var (
+	postsSearchView = newSearchView(database.PostSearchResult{},
+		"id", "categoryName", "categoryId", "title", "song", "artists", "diff", "level",
+		"time", "author", "likes", "tags",
+	)
+	songsSearchView = newSearchView(database.SongSearchResult{},
+		"id", "musicTitle", "tag", "bandId", "jacketImage", "publishedAt",
+		"difficulty", "length", "notes", "bpm",
+	)
+)
+
+// jsonFields 获取结构体序列化后的顶层字段名，展开未命名的嵌入结构体
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		var names []string
+		switch {
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			names = jsonFields(field.Type)
+		case !field.IsExported():
+			continue
+		case name == "":
+			names = []string{field.Name}
+		default:
+			names = []string{name}
+		}
+		for _, name := range names {
+			if !slices.Contains(fields, name) {
+				fields = append(fields, name)
+			}
+		}
+	}
+	return fields
+}
+
+// project 解析 fields 参数，支持 summary（默认）、all 与逗号分隔的字段列表
+//
+// 返回的字段总是包含 id
+func (v searchView) project(param string) ([]string, error) {
+	switch strings.TrimSpace(param) {
+	case "", "summary":
+		return slices.Clone(v.summary), nil
+	case "all":
+		return slices.Clone(v.fields), nil
+	}
+
+	fields := []string{"id"}
+	for field := range strings.SplitSeq(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(v.fields, field) {
+			return nil, fmt.Errorf("无效的字段: %s", field)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// mongoFields 将响应字段名转换为数据库中的字段名
+func mongoFields(fields []string) []string {
+	result := make([]string, len(fields))
+	for i, field := range fields {
+		if field == "id" {
+			field = "_id"
+		}
+		result[i] = field
+	}
+	return result
+}
+
+// projectResults 按字段列表裁剪搜索结果，字段的顺序与类型与搜索结果类型序列化后一致
+func projectResults[T any](results []T, fields []string) ([]json.RawMessage, error) {
+	projected := make([]json.RawMessage, len(results))
+	for i, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		if projected[i], err = filterObject(data, fields); err != nil {
+			return nil, err
+		}
+	}
+	return projected, nil
+}
+
+// filterObject 保留 JSON 对象中指定的顶层字段，不改变字段顺序
+func filterObject(data []byte, fields []string) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		if !slices.Contains(fields, key) {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		encodedKey, _ := json.Marshal(key)
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"time"
+
+	"anon-bestdori-database/data"
+	"anon-bestdori-database/database"
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/version"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readyCheck 单项就绪检查结果
+type readyCheck struct {
+	OK        bool    `json:"ok"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// runReadyCheck 执行一项就绪检查并计时
+func runReadyCheck(fn func() error) readyCheck {
+	start := time.Now()
+	err := fn()
+	check := readyCheck{
+		OK:        err == nil,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func registerHealthRoutes(router fiber.Router, db *database.Database, updater *data.DataUpdater) {
+	// 进程存活
+	router.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	// 数据库可达、资源存储可写且数据更新器未处于故障状态
+	router.Get("/readyz", func(c *fiber.Ctx) error {
+		ready := true
+
+		mongo := runReadyCheck(func() error {
+			return db.Ping(2)
+		})
+		ready = ready && mongo.OK
+
+		assets := runReadyCheck(files.CheckWritable)
+		ready = ready && assets.OK
+
+		result := fiber.Map{
+			"mongo":  mongo,
+			"assets": assets,
+		}
+		if updater != nil {
+			status := updater.Status()
+			ready = ready && !status.Fatal
+			result["updater"] = status
+		}
+
+		if !ready {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"status": "unavailable",
+				"checks": result,
+			})
+		}
+		return c.JSON(fiber.Map{
+			"status": "ready",
+			"checks": result,
+		})
+	})
+
+	// 版本与构建信息
+	router.Get("/version", func(c *fiber.Ctx) error {
+		return c.JSON(version.Build())
+	})
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/songs"
+	"github.com/gofiber/fiber/v2"
+
+	"anon-bestdori-database/data"
+	"anon-bestdori-database/database"
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+)
+
+type AssetsManifestParams struct {
+	Category string `query:"category"`
+	SongId   *int   `query:"songId"`
+	Since    string `query:"since"`
+}
+
+type AssetsManifestItem struct {
+	Name        string    `json:"name"`
+	Category    string    `json:"category"`
+	URL         string    `json:"url"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256,omitempty"`
+	ContentType string    `json:"contentType"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// parseSince 解析 since 参数，支持 RFC 3339 时间与 Unix 秒级时间戳
+func parseSince(s string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func getAssetsManifestHandler(db *database.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		params := AssetsManifestParams{}
+		if err := c.QueryParser(&params); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "无效的查询参数"})
+		}
+
+		var categories []string
+		if params.Category != "" {
+			for name := range strings.SplitSeq(params.Category, ",") {
+				name = strings.TrimSpace(name)
+				if _, ok := files.GetAssetsCategory(name); !ok {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "未知的资源类别 " + name})
+				}
+				categories = append(categories, name)
+			}
+		}
+
+		var since time.Time
+		if params.Since != "" {
+			t, err := parseSince(params.Since)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "无效的 since 参数"})
+			}
+			since = t
+		}
+
+		var songAssets []string
+		if params.SongId != nil {
+			info, err := db.GetSongByID(c.UserContext(), *params.SongId)
+			if err != nil {
+				log.FromContext(c.UserContext()).Errorf("failed to get song %d: %v", *params.SongId, err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
+			}
+			if info == nil {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"result": false, "error": "歌曲未找到"})
+			}
+			songAssets = data.SongAssetsNames(&songs.Song{Id: *params.SongId, Info: info})
+		}
+
+		// 使用内存中的资源清单，避免每次请求都列出存储后端中的全部资源
+		entries := files.ManifestEntries()
+		items := make([]AssetsManifestItem, 0, len(entries))
+		for _, name := range slices.Sorted(maps.Keys(entries)) {
+			if files.IsVariantName(name) {
+				continue
+			}
+			category, _, _ := strings.Cut(name, "/")
+			if categories != nil && !slices.Contains(categories, category) {
+				continue
+			}
+			if songAssets != nil && !slices.Contains(songAssets, name) {
+				continue
+			}
+
+			entry := entries[name]
+			if entry.UpdatedAt.Before(since) {
+				continue
+			}
+			items = append(items, AssetsManifestItem{
+				Name:        name,
+				Category:    category,
+				URL:         "/assets/" + name,
+				Size:        entry.Size,
+				SHA256:      entry.SHA256,
+				ContentType: contentTypeByExt(name),
+				UpdatedAt:   entry.UpdatedAt,
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"result": true,
+			"count":  len(items),
+			"assets": items,
+		})
+	}
+}
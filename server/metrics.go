@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "anon",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "anon",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// metricsMiddleware 按路由模板统计请求数与延迟，避免路径参数导致标签膨胀
+func metricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	middleware := c.Route()
+
+	err := c.Next()
+
+	// 错误由全局错误处理器在中间件返回后写入状态码，这里提前推算
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+
+	// 未匹配任何路由时仍停留在中间件上
+	route := c.Route().Path
+	if c.Route() == middleware {
+		route = "unmatched"
+	}
+
+	labels := []string{c.Method(), route, strconv.Itoa(status)}
+	httpRequests.WithLabelValues(labels...).Inc()
+	httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+func registerMetricsRoutes(router fiber.Router) {
+	handler := fasthttpadaptor.NewFastHTTPHandler(promhttp.Handler())
+	router.Get("/metrics", func(c *fiber.Ctx) error {
+		handler(c.Context())
+		return nil
+	})
+}
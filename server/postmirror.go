@@ -0,0 +1,56 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
+	"github.com/gofiber/fiber/v2"
+
+	"anon-bestdori-database/database"
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+)
+
+// postMirrorURL 获取镜像资源的访问链接
+func postMirrorURL(baseURL, name string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/assets/" + name
+}
+
+// applyPostMirrors 将帖子中自定义歌曲的音频与封面链接替换为镜像链接
+//
+// 仅在镜像启用、上游链接与镜像时一致且镜像资源仍在资源清单中时替换，否则保留上游链接
+func applyPostMirrors(c *fiber.Ctx, db *database.Database, posts map[int]*dto.PostInfo) {
+	mirrorConf := requestConfig(c).Assets.Mirror
+	if !mirrorConf.Enabled {
+		return
+	}
+
+	ids := make([]int, 0, len(posts))
+	for id, post := range posts {
+		if post != nil && post.Song != nil && post.Song.Type == dto.PostSongTypeCustom {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	mirrors, err := db.GetPostMirrorsByIDs(c.UserContext(), ids)
+	if err != nil {
+		log.FromContext(c.UserContext()).Errorf("failed to get post mirrors %v: %v", ids, err)
+		return
+	}
+	for id, mirror := range mirrors {
+		song := posts[id].Song
+		for kind, url := range map[string]*string{"audio": &song.Audio, "cover": &song.Cover} {
+			mirrored, ok := mirror.Assets[kind]
+			if !ok || mirrored.Source != *url {
+				continue
+			}
+			if _, ok := files.GetManifestEntry(mirrored.Name); !ok {
+				continue
+			}
+			*url = postMirrorURL(mirrorConf.BaseURL, mirrored.Name)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"anon-bestdori-database/config"
 	"anon-bestdori-database/database"
+	"anon-bestdori-database/pkg/log"
+	"slices"
 	"strconv"
 
+	"github.com/WindowsSov8forUs/bestdori-api-go/bestdori/dto"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -26,11 +30,17 @@ type PostsSearchParams struct {
 	Length    float64  `query:"length"`
 	LengthMin float64  `query:"length_min"`
 	LengthMax float64  `query:"length_max"`
+	// 返回字段：summary（默认）、all 或逗号分隔的字段列表
+	Fields string `query:"fields"`
+	// 是否返回谱面，谱面较大，默认不返回，可通过 /posts/:id 获取
+	IncludeChart bool `query:"includeChart"`
 }
 
 func registerPostsRouter(router fiber.Router, db *database.Database) {
-	router.Get("/search", getPostSearchHandler(db))
-	router.Get("/:id", getPostIDHandler(db))
+	router.Get("/search", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.PostsSearch }, getPostSearchHandler(db))...)
+	router.Get("/batch", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.Posts }, getPostsBatchHandler(db))...)
+	router.Post("/batch", uncachedJSONHandlers(getPostsBatchHandler(db))...)
+	router.Get("/:id", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.Posts }, getPostIDHandler(db))...)
 }
 
 func getPostIDHandler(db *database.Database) fiber.Handler {
@@ -41,13 +51,15 @@ func getPostIDHandler(db *database.Database) fiber.Handler {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "无效的 ID 格式"})
 		}
 
-		post, err := db.GetPostByID(c.Context(), id)
+		post, err := db.GetPostByID(c.UserContext(), id)
 		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to get post %d: %v", id, err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
 		}
 		if post == nil {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"result": false, "error": "帖子未找到"})
 		}
+		applyPostMirrors(c, db, map[int]*dto.PostInfo{id: post})
 
 		return c.JSON(fiber.Map{
 			"result": true,
@@ -56,12 +68,39 @@ func getPostIDHandler(db *database.Database) fiber.Handler {
 	}
 }
 
+func getPostsBatchHandler(db *database.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ids, keys, err := parseBatchIntIDs(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+
+		posts, err := db.GetPostsByIDs(c.UserContext(), ids)
+		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to get posts %v: %v", ids, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+		applyPostMirrors(c, db, posts)
+
+		return c.JSON(batchResponse(keys, ids, posts, "post", "帖子未找到"))
+	}
+}
+
 func getPostSearchHandler(db *database.Database) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		params := PostsSearchParams{}
 		if err := c.QueryParser(&params); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "无效的查询参数"})
 		}
+		fields, err := postsSearchView.project(params.Fields)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+		// includeChart 在字段投影之后生效，为 false 时即使在 fields 中指定也不返回谱面
+		fields = slices.DeleteFunc(fields, func(field string) bool { return field == "chart" })
+		if params.IncludeChart {
+			fields = append(fields, "chart")
+		}
 
 		filters := []bson.M{}
 		// keyword
@@ -197,8 +236,21 @@ func getPostSearchHandler(db *database.Database) fiber.Handler {
 			searchFilter = bson.M{"$and": filters}
 		}
 
-		posts, err := db.SearchPosts(c.Context(), searchFilter)
+		results, err := db.SearchPosts(c.UserContext(), searchFilter, mongoFields(fields))
+		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to search posts: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+		if slices.Contains(fields, "song") {
+			mirrored := make(map[int]*dto.PostInfo, len(results))
+			for i := range results {
+				mirrored[results[i].ID] = &results[i].PostInfo
+			}
+			applyPostMirrors(c, db, mirrored)
+		}
+		posts, err := projectResults(results, fields)
 		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to project post search results: %v", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
 		}
 
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindow 单个限流对象在当前窗口内的计数
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// rateLimiter 固定窗口限流器
+type rateLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{windows: map[string]*rateWindow{}}
+}
+
+// allow 记录一次请求并检查是否超过限制
+//
+// 返回：是否允许、窗口内剩余次数和窗口重置时间
+func (l *rateLimiter) allow(key string, limit int, window time.Duration) (bool, int, time.Time) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now, window)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	reset := w.start.Add(window)
+	if w.count >= limit {
+		return false, 0, reset
+	}
+	w.count++
+	return true, limit - w.count, reset
+}
+
+// sweep 每个窗口周期清理一次已过期的计数，调用方需持有 mu
+func (l *rateLimiter) sweep(now time.Time, window time.Duration) {
+	if now.Sub(l.lastSweep) < window {
+		return
+	}
+	l.lastSweep = now
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= window {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"anon-bestdori-database/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+)
+
+var (
+	compressHandler = compress.New(compress.Config{Level: compress.LevelDefault})
+	// ETag 基于未压缩的内容计算，压缩前后的响应体不同，只能作为弱 ETag
+	etagHandler = etag.New(etag.Config{Weak: true})
+)
+
+// compressMiddleware 按配置压缩响应，配置在每次请求时读取，热重载后立即生效
+func compressMiddleware(c *fiber.Ctx) error {
+	if !requestConfig(c).Server.Compress {
+		return c.Next()
+	}
+	return compressHandler(c)
+}
+
+// jsonHandlers 为 JSON 接口附加响应压缩、基于内容摘要的 ETag 与 Cache-Control
+func jsonHandlers(cacheControl func(config.CacheControlConfig) string, handler fiber.Handler) []fiber.Handler {
+	return []fiber.Handler{
+		compressMiddleware,
+		// If-None-Match 匹配时返回 304
+		etagHandler,
+		func(c *fiber.Ctx) error {
+			err := c.Next()
+			if value := cacheControl(requestConfig(c).Server.CacheControl); value != "" && err == nil && c.Response().StatusCode() == fiber.StatusOK {
+				c.Set(fiber.HeaderCacheControl, value)
+			}
+			return err
+		},
+		handler,
+	}
+}
+
+// uncachedJSONHandlers 为不应被缓存的 JSON 接口附加响应压缩，不计算 ETag
+func uncachedJSONHandlers(handler fiber.Handler) []fiber.Handler {
+	return []fiber.Handler{compressMiddleware, handler}
+}
@@ -2,72 +2,140 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"time"
 
+	"anon-bestdori-database/config"
 	"anon-bestdori-database/data"
 	"anon-bestdori-database/database"
 	"anon-bestdori-database/pkg/log"
-	"anon-bestdori-database/version"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// defaultShutdownTimeout 未配置关闭超时时的默认值
+const defaultShutdownTimeout = 30 * time.Second
+
 type Server struct {
 	app      *fiber.App
+	conf     *config.Config
 	database *database.Database
 	updater  *data.DataUpdater
 }
 
+// maxRequestIDLength 接受的外部请求 ID 最大长度
+const maxRequestIDLength = 128
+
+// validRequestID 检查外部传入的请求 ID 是否仅包含可打印 ASCII 字符
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成随机请求 ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDMiddleware 沿用或生成 X-Request-ID，写入响应头并附加到请求上下文
+func requestIDMiddleware(c *fiber.Ctx) error {
+	id := c.Get(fiber.HeaderXRequestID)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	c.Set(fiber.HeaderXRequestID, id)
+	c.SetUserContext(log.WithRequestID(c.UserContext(), id))
+	return c.Next()
+}
+
 func loggerMiddleware(c *fiber.Ctx) error {
 	start := time.Now()
-	log.Infof("REQ START %s %s", c.Method(), c.Path())
+	logger := log.FromContext(c.UserContext())
+	logger.Infof("REQ START %s %s", c.Method(), c.Path())
 
 	err := c.Next()
 
 	status := c.Response().StatusCode()
 	latency := time.Since(start)
-	log.Infof("REQ END %s %s %d %.3fms", c.Method(), c.Path(), status, latency.Seconds()*1000)
+	logger.Infof("REQ END %s %s %d %.3fms", c.Method(), c.Path(), status, latency.Seconds()*1000)
 
 	return err
 }
 
-func corsMiddleware(c *fiber.Ctx) error {
-	c.Response().Header.Set("Access-Control-Allow-Origin", "*")
-	c.Response().Header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	c.Response().Header.Set("Access-Control-Allow-Headers", "*")
-	c.Response().Header.Set("Server-Version", "Anon-Database/"+version.Version)
-	if c.Method() == "OPTIONS" {
-		return c.SendStatus(fiber.StatusNoContent)
-	}
+// configLocalsKey 请求的配置快照在 Locals 中的键
+const configLocalsKey = "config"
+
+// configMiddleware 在请求开始时获取一次配置快照，同一请求内的中间件与处理函数使用同一份配置
+func (s *Server) configMiddleware(c *fiber.Ctx) error {
+	c.Locals(configLocalsKey, s.currentConfig())
 	return c.Next()
 }
 
-func New(db *database.Database, updater *data.DataUpdater) *Server {
+// requestConfig 获取请求开始时的配置快照
+func requestConfig(c *fiber.Ctx) *config.Config {
+	if conf, ok := c.Locals(configLocalsKey).(*config.Config); ok {
+		return conf
+	}
+	return config.Get()
+}
+
+// New 创建 HTTP 服务
+//
+// conf 仅用于创建时确定的设置，请求处理时读取 config.Get() 发布的最新配置快照
+func New(conf *config.Config, db *database.Database, updater *data.DataUpdater) *Server {
 	app := fiber.New(fiber.Config{
 		ServerHeader: "anon-bestdori-database",
 		AppName:      "Anon Bestdori Database",
+		ProxyHeader:  conf.Server.ProxyHeader,
 	})
+	s := &Server{
+		app:      app,
+		conf:     conf,
+		database: db,
+		updater:  updater,
+	}
+
+	// 探针与指标路由注册在中间件之前，避免频繁探测刷屏日志
+	registerHealthRoutes(app, db, updater)
+	registerMetricsRoutes(app)
 
 	// 中间件
+	app.Use(requestIDMiddleware)
+	app.Use(s.configMiddleware)
+	app.Use(metricsMiddleware)
 	app.Use(loggerMiddleware)
 	app.Use(corsMiddleware)
 
 	// 路由注册
-	registerPostsRouter(app.Group("/posts"), db)
-	registerSongsRoutes(app.Group("/songs"), db)
-	registerChartsRoutes(app.Group("/charts"), db)
-	registerAssetsRoutes(app.Group("/assets"), db)
-
-	s := &Server{
-		app:      app,
-		database: db,
-		updater:  updater,
-	}
+	auth := newAuthenticator(db)
+	registerPostsRouter(app.Group("/posts", auth.middleware("posts")), db)
+	registerSongsRoutes(app.Group("/songs", auth.middleware("songs")), db)
+	registerChartsRoutes(app.Group("/charts", auth.middleware("charts")), db)
+	registerAssetsRoutes(app.Group("/assets", auth.middleware("assets")), db)
 
 	return s
 }
 
+// currentConfig 获取最新的配置快照，尚未发布时使用创建服务时的配置
+func (s *Server) currentConfig() *config.Config {
+	if conf := config.Get(); conf != nil {
+		return conf
+	}
+	return s.conf
+}
+
 func (s *Server) UpdateSongByID(id int) (bool, error) {
 	if s.updater == nil {
 		return false, fmt.Errorf("data updater not configured")
@@ -83,9 +151,38 @@ func (s *Server) UpdatePostByID(id int) (bool, error) {
 }
 
 func (s *Server) Start(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve 在已建立的监听上提供服务
+//
+// ctx 取消后停止接受新请求，等待进行中的请求完成或超过 server.shutdown_timeout 后返回
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	errCh := make(chan error, 1)
 	go func() {
-		<-ctx.Done()
-		_ = s.app.Shutdown()
+		errCh <- s.app.Listener(ln)
 	}()
-	return s.app.Listen(addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	err := s.app.ShutdownWithTimeout(s.shutdownTimeout())
+	<-errCh
+	return err
+}
+
+// shutdownTimeout 获取关闭服务时等待请求完成的最长时间
+func (s *Server) shutdownTimeout() time.Duration {
+	conf := s.currentConfig()
+	if conf.Server.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(conf.Server.ShutdownTimeout) * time.Second
 }
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strconv"
 
+	"anon-bestdori-database/config"
 	"anon-bestdori-database/database"
+	"anon-bestdori-database/pkg/log"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
@@ -27,11 +29,15 @@ type SongsSearchParams struct {
 	LengthMax float64 `query:"length_max"`
 	BandId    int     `query:"bandId"`
 	Tag       string  `query:"tag"`
+	// 返回字段：summary（默认）、all 或逗号分隔的字段列表
+	Fields string `query:"fields"`
 }
 
 func registerSongsRoutes(router fiber.Router, db *database.Database) {
-	router.Get("/search", getSongsSearchHandler(db))
-	router.Get("/:id", getSongsIDHandler(db))
+	router.Get("/search", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.SongsSearch }, getSongsSearchHandler(db))...)
+	router.Get("/batch", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.Songs }, getSongsBatchHandler(db))...)
+	router.Post("/batch", uncachedJSONHandlers(getSongsBatchHandler(db))...)
+	router.Get("/:id", jsonHandlers(func(cc config.CacheControlConfig) string { return cc.Songs }, getSongsIDHandler(db))...)
 }
 
 func getSongsIDHandler(db *database.Database) fiber.Handler {
@@ -42,8 +48,9 @@ func getSongsIDHandler(db *database.Database) fiber.Handler {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "无效的 ID 格式"})
 		}
 
-		song, err := db.GetSongByID(c.Context(), id)
+		song, err := db.GetSongByID(c.UserContext(), id)
 		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to get song %d: %v", id, err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
 		}
 		if song == nil {
@@ -57,12 +64,33 @@ func getSongsIDHandler(db *database.Database) fiber.Handler {
 	}
 }
 
+func getSongsBatchHandler(db *database.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ids, keys, err := parseBatchIntIDs(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+
+		songs, err := db.GetSongsByIDs(c.UserContext(), ids)
+		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to get songs %v: %v", ids, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+
+		return c.JSON(batchResponse(keys, ids, songs, "song", "歌曲未找到"))
+	}
+}
+
 func getSongsSearchHandler(db *database.Database) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		params := SongsSearchParams{}
 		if err := c.QueryParser(&params); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": "无效的查询参数"})
 		}
+		fields, err := songsSearchView.project(params.Fields)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
 
 		filters := []bson.M{}
 		// keyword
@@ -213,8 +241,14 @@ func getSongsSearchHandler(db *database.Database) fiber.Handler {
 			searchFilter = bson.M{"$and": filters}
 		}
 
-		songs, err := db.SearchSongs(c.Context(), searchFilter)
+		results, err := db.SearchSongs(c.UserContext(), searchFilter, mongoFields(fields))
+		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to search songs: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
+		}
+		songs, err := projectResults(results, fields)
 		if err != nil {
+			log.FromContext(c.UserContext()).Errorf("failed to project song search results: %v", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"result": false, "error": err.Error()})
 		}
 
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"anon-bestdori-database/files"
+	"anon-bestdori-database/pkg/log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readCloser 组合读取与关闭，用于截断后的资源流
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// sendStream 以流的形式发送资源，支持单区间 Range 请求与条件请求
+//
+// reader 会在响应发送完毕后关闭
+func sendStream(c *fiber.Ctx, reader io.ReadSeekCloser, info files.AssetsInfo, contentType, cacheControl string) error {
+	c.Set(fiber.HeaderContentType, contentType)
+	// 资源中包含镜像的外部文件，禁止浏览器按内容嗅探类型
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, cacheControl)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if info.ETag != "" {
+		c.Set(fiber.HeaderETag, info.ETag)
+	}
+	if !info.ModTime.IsZero() {
+		c.Set(fiber.HeaderLastModified, info.ModTime.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(c, info) {
+		reader.Close()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if rangeHeader != "" && !ifRangeMatches(c, info) {
+		rangeHeader = ""
+	}
+
+	start, length, partial, ok := parseRange(rangeHeader, info.Size)
+	if !ok {
+		reader.Close()
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+		return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+	}
+	if !partial {
+		c.Status(fiber.StatusOK)
+		c.Response().SetBodyStream(reader, int(info.Size))
+		return nil
+	}
+
+	if _, err := reader.Seek(start, io.SeekStart); err != nil {
+		reader.Close()
+		log.FromContext(c.UserContext()).Errorf("failed to seek assets stream: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	c.Status(fiber.StatusPartialContent)
+	c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, info.Size))
+	c.Response().SetBodyStream(readCloser{io.LimitReader(reader, length), reader}, int(length))
+	return nil
+}
+
+// isNotModified 检查 If-None-Match 与 If-Modified-Since
+func isNotModified(c *fiber.Ctx, info files.AssetsInfo) bool {
+	if inm := c.Get(fiber.HeaderIfNoneMatch); inm != "" {
+		return info.ETag != "" && etagMatches(inm, info.ETag)
+	}
+	if ims := c.Get(fiber.HeaderIfModifiedSince); ims != "" && !info.ModTime.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !info.ModTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// ifRangeMatches 检查 If-Range，不匹配时应忽略 Range 并返回完整内容
+func ifRangeMatches(c *fiber.Ctx, info files.AssetsInfo) bool {
+	ir := c.Get(fiber.HeaderIfRange)
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		// If-Range 要求强比较
+		return !strings.HasPrefix(ir, "W/") && ir == info.ETag
+	}
+	t, err := http.ParseTime(ir)
+	if err != nil {
+		return false
+	}
+	return info.ModTime.Truncate(time.Second).Equal(t)
+}
+
+// etagMatches 以弱比较检查 If-None-Match 是否包含指定 ETag
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange 解析 Range 请求头
+//
+// 仅支持单个 bytes 区间，无 Range、格式错误或包含多个区间时 partial 为 false，返回完整内容；
+// 区间无法满足时 ok 为 false
+func parseRange(header string, size int64) (start, length int64, partial, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size, false, true
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, size, false, true
+	}
+
+	if first == "" {
+		// 后缀区间：bytes=-N
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, false, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, false, true
+	}
+	if start >= size {
+		return 0, 0, false, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, size, false, true
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, true, true
+}
@@ -0,0 +1,51 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// bestdoriAPIModule 上游接口库的模块路径
+const bestdoriAPIModule = "github.com/WindowsSov8forUs/bestdori-api-go"
+
+// BuildInfo 构建信息
+type BuildInfo struct {
+	Version     string `json:"version"`
+	GoVersion   string `json:"goVersion"`
+	Revision    string `json:"revision,omitempty"`
+	BuildTime   string `json:"buildTime,omitempty"`
+	Modified    bool   `json:"modified,omitempty"`
+	BestdoriAPI string `json:"bestdoriApi,omitempty"`
+}
+
+// Build 获取当前程序的构建信息
+func Build() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.BuildTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == bestdoriAPIModule {
+			info.BestdoriAPI = dep.Version
+			if dep.Replace != nil {
+				info.BestdoriAPI = dep.Replace.Version
+			}
+		}
+	}
+	return info
+}